@@ -0,0 +1,365 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gen-client-models generates the OpenFaaS client's model types from the schemas in the gateway's OpenAPI spec.
+// Swagger 2.0 specs, whose schemas are listed under definitions, are supported as well. Specs may be written in YAML
+// or JSON.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// schema is the subset of an OpenAPI schema object understood by the generator.
+type schema struct {
+	Description          string         `json:"description"`
+	Type                 string         `json:"type"`
+	Format               string         `json:"format"`
+	Ref                  string         `json:"$ref"`
+	AllOf                []*schema      `json:"allOf"`
+	Items                *schema        `json:"items"`
+	AdditionalProperties *schema        `json:"additionalProperties"`
+	Properties           orderedSchemas `json:"properties"`
+	Required             []string       `json:"required"`
+	OmitEmpty            *bool          `json:"x-omitempty"`
+	GoName               string         `json:"x-go-name"`
+}
+
+// orderedSchemas is a JSON object of schemas that remembers the order of its keys, so that generated fields appear
+// in the same order as in the spec.
+type orderedSchemas struct {
+	keys    []string
+	schemas map[string]*schema
+}
+
+func (o *orderedSchemas) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("expected an object")
+	}
+	o.schemas = map[string]*schema{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var s schema
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("%v: %v", key, err)
+		}
+		o.keys, o.schemas[key] = append(o.keys, key), &s
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// spec is the subset of an OpenAPI or swagger spec understood by the generator.
+type spec struct {
+	Components struct {
+		Schemas orderedSchemas `json:"schemas"`
+	} `json:"components"`
+	Definitions orderedSchemas `json:"definitions"`
+}
+
+// schemas returns the spec's named schemas and the prefix of references to them.
+func (s *spec) schemas() (*orderedSchemas, string) {
+	if len(s.Components.Schemas.keys) != 0 {
+		return &s.Components.Schemas, "#/components/schemas/"
+	}
+	return &s.Definitions, "#/definitions/"
+}
+
+// initialisms are the words that Go names spell in upper case.
+var initialisms = map[string]bool{"cpu": true, "id": true, "url": true, "http": true, "json": true}
+
+// goName returns the exported Go name for the given JSON name.
+func goName(name string) string {
+	var words []string
+	start := 0
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words, start = append(words, name[start:i]), i
+		}
+	}
+	words = append(words, name[start:])
+
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+		} else {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
+
+// goType returns the Go type for the given schema. References must refer to one of the given types, whose names are
+// prefixed with the given prefix.
+func goType(s *schema, prefix string, types map[string]bool) (string, error) {
+	// A reference that is wrapped in allOf, e.g. to mark it nullable, is treated like the reference itself.
+	if len(s.AllOf) == 1 && s.Type == "" {
+		return goType(s.AllOf[0], prefix, types)
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, prefix)
+		if name == s.Ref {
+			return "", fmt.Errorf("unsupported reference %q", s.Ref)
+		}
+		if !types[name] {
+			return "", fmt.Errorf("reference to %v, which is not generated", name)
+		}
+		return "*" + name, nil
+	}
+
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "*time.Time", nil
+		}
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "number":
+		return "float64", nil
+	case "integer":
+		switch s.Format {
+		case "int32", "int64", "uint32", "uint64":
+			return s.Format, nil
+		}
+		return "int", nil
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		elem, err := goType(s.Items, prefix, types)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if s.AdditionalProperties == nil {
+			return "", fmt.Errorf("objects must be definitions or have additionalProperties")
+		}
+		elem, err := goType(s.AdditionalProperties, prefix, types)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + elem, nil
+	}
+	return "", fmt.Errorf("unsupported type %q", s.Type)
+}
+
+// columns returns the width of the given line, with tabs expanded to four columns as by the linter.
+func columns(line string) int {
+	return len(line) + 3*strings.Count(line, "\t")
+}
+
+// writeComment writes the given description as a Go comment, wrapped at 120 columns.
+func writeComment(b *bytes.Buffer, indent, description string) {
+	if description == "" {
+		return
+	}
+	line := indent + "//"
+	for _, word := range strings.Fields(description) {
+		if columns(line)+1+len(word) > 120 && line != indent+"//" {
+			b.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+}
+
+// fieldComment returns the comment for the field with the given name and description. Descriptions that omit their
+// subject, e.g. "is the total memory usage", are prefixed with the field's name.
+func fieldComment(field, description string) string {
+	if strings.HasPrefix(description, "is ") || strings.HasPrefix(description, "are ") {
+		return field + " " + description
+	}
+	return description
+}
+
+// generate generates the Go source for the given types, which must be among the given spec's schemas. The types are
+// generated in the order in which they appear in the spec.
+func generate(s *spec, source, pkg string, types []string) ([]byte, error) {
+	schemas, prefix := s.schemas()
+	generated := map[string]bool{}
+	for _, name := range types {
+		if _, ok := schemas.schemas[name]; !ok {
+			return nil, fmt.Errorf("%v: no such schema", name)
+		}
+		generated[name] = true
+	}
+
+	var body bytes.Buffer
+	usesTime := false
+	for _, name := range schemas.keys {
+		if !generated[name] {
+			continue
+		}
+		def := schemas.schemas[name]
+		if def.Type != "object" {
+			return nil, fmt.Errorf("%v: only object definitions are supported", name)
+		}
+
+		body.WriteString("\n")
+		if def.Description != "" {
+			writeComment(&body, "", def.Description)
+		} else {
+			writeComment(&body, "", fmt.Sprintf("%s is the %s schema of the gateway API.", name, name))
+		}
+		fmt.Fprintf(&body, "type %s struct {\n", name)
+		required := map[string]bool{}
+		for _, prop := range def.Required {
+			required[prop] = true
+		}
+		for _, prop := range def.Properties.keys {
+			p := def.Properties.schemas[prop]
+			typ, err := goType(p, prefix, generated)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%v: %v", name, prop, err)
+			}
+			usesTime = usesTime || strings.Contains(typ, "time.Time")
+
+			field := p.GoName
+			if field == "" {
+				field = goName(prop)
+			}
+			// Optional properties are omitted when empty unless the spec says otherwise.
+			omitEmpty := !required[prop]
+			if p.OmitEmpty != nil {
+				omitEmpty = *p.OmitEmpty
+			}
+			tag := prop
+			if omitEmpty {
+				tag += ",omitempty"
+			}
+			writeComment(&body, "\t", fieldComment(field, p.Description))
+			fmt.Fprintf(&body, "\t%s %s `json:%q`\n", field, typ, tag)
+		}
+		body.WriteString("}\n")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen-client-models from %s. DO NOT EDIT.\n\npackage %s\n", source, pkg)
+	if usesTime {
+		b.WriteString("\nimport \"time\"\n")
+	}
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+// writeJSON writes the given YAML value as JSON. Mappings are written in their original order, as the order of
+// properties determines the order of the generated fields.
+func writeJSON(b *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		b.WriteString("{")
+		for i, item := range v {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteString(":")
+			if err = writeJSON(b, item.Value); err != nil {
+				return err
+			}
+		}
+		b.WriteString("}")
+	case []interface{}:
+		b.WriteString("[")
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if err := writeJSON(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteString("]")
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(j)
+	}
+	return nil
+}
+
+// parseSpec parses the given YAML or JSON spec.
+func parseSpec(b []byte) (*spec, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var j bytes.Buffer
+	if err := writeJSON(&j, doc); err != nil {
+		return nil, err
+	}
+	var s spec
+	if err := json.Unmarshal(j.Bytes(), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func main() {
+	specPath := flag.String("spec", "", "the path of the OpenAPI or swagger spec")
+	out := flag.String("out", "", "the path of the generated file")
+	pkg := flag.String("package", "", "the package of the generated file")
+	types := flag.String("types", "", "a comma-separated list of the schemas to generate types for")
+	flag.Parse()
+	if *specPath == "" || *out == "" || *pkg == "" || *types == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*specPath, *out, *pkg, strings.Split(*types, ",")); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, out, pkg string, types []string) error {
+	b, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+	s, err := parseSpec(b)
+	if err != nil {
+		return fmt.Errorf("parsing %v: %v", specPath, err)
+	}
+	src, err := generate(s, specPath, pkg, types)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
@@ -0,0 +1,50 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// pulumi-gen-openfaas writes the provider's Pulumi package schema, which is used to generate the language SDKs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/provider"
+	"github.com/pulumi/pulumi-openfaas/pkg/version"
+)
+
+var packageName = "openfaas"
+
+func main() {
+	out := flag.String("out", "", "the file to write the schema to (defaults to stdout)")
+	flag.Parse()
+
+	schema, err := provider.PackageSchema(packageName, version.Version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	schema = append(schema, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(schema)
+	} else {
+		err = ioutil.WriteFile(*out, schema, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+)
+
+// FunctionsAPI is the subset of the OpenFaaS API used to manage functions. It is implemented by Client, and may be
+// implemented by fakes for testing or by wrappers that add behavior to a Client.
+type FunctionsAPI interface {
+	// CreateFunction creates a new function from the given function specification.
+	CreateFunction(ctx context.Context, f *Function) error
+	// GetFunction gets the function with the given name and namespace. It returns ErrNotFound if there is no such
+	// function.
+	GetFunction(ctx context.Context, name, namespace string) (*Function, error)
+	// ListFunctions lists the functions in the given namespace.
+	ListFunctions(ctx context.Context, namespace string) ([]Function, error)
+	// ListFunctionsWithLabels lists the functions in the given namespace that carry all of the given labels with the
+	// given values.
+	ListFunctionsWithLabels(ctx context.Context, namespace string, labels map[string]string) ([]Function, error)
+	// UpdateFunction updates the function with the given specification.
+	UpdateFunction(ctx context.Context, f *Function) error
+	// DeleteFunction deletes the function with the given name and namespace.
+	DeleteFunction(ctx context.Context, name, namespace string) error
+	// ScaleFunction sets the number of replicas of the function with the given name and namespace.
+	ScaleFunction(ctx context.Context, name, namespace string, replicas uint64) error
+	// Healthz checks that the gateway is reachable and healthy.
+	Healthz(ctx context.Context) error
+}
+
+var _ FunctionsAPI = (*Client)(nil)
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitOpenError is returned for requests that are rejected without being issued because the circuit breaker has
+// determined that the gateway is unreachable.
+type CircuitOpenError struct {
+	// Failures is the number of consecutive requests that failed to reach the gateway.
+	Failures int
+	// RetryAt is the time after which a request is permitted to test whether the gateway is reachable again.
+	RetryAt time.Time
+	// Last is the error that caused the most recent failure.
+	Last error
+}
+
+// Error returns a description of the error.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("gateway unreachable: the last %d requests failed to connect (last error: %v); failing fast "+
+		"until %v", e.Failures, e.Last, e.RetryAt.Format(time.RFC3339))
+}
+
+// CircuitBreaker fails requests fast once a number of consecutive requests have failed to reach the gateway, so that
+// a gateway outage is reported promptly rather than after every pending request has timed out on its own. After a
+// cooldown, a single request is permitted to test whether the gateway has recovered; if it succeeds, the breaker
+// closes again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	lock     sync.Mutex
+	failures int       // The number of consecutive connection failures.
+	last     error     // The most recent connection failure.
+	openedAt time.Time // The time at which the breaker opened. Zero if the breaker is closed.
+	probing  bool      // True if a request is testing whether the gateway has recovered.
+}
+
+// NewCircuitBreaker creates a circuit breaker that opens after the given number of consecutive connection failures
+// and permits a request to test the gateway after the given cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns nil if a request may be issued, or the error with which to reject it.
+func (b *CircuitBreaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	retryAt := b.openedAt.Add(b.cooldown)
+	if !b.probing && !time.Now().Before(retryAt) {
+		b.probing = true
+		return nil
+	}
+	return &CircuitOpenError{Failures: b.failures, RetryAt: retryAt, Last: b.last}
+}
+
+// record records the outcome of a request. Only failures to reach the gateway count against it: any response, even
+// an error response, shows that the gateway is reachable.
+func (b *CircuitBreaker) record(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures, b.last, b.openedAt = 0, nil, time.Time{}
+		return
+	}
+
+	b.failures, b.last = b.failures+1, err
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// WithCircuitBreaker causes the client's requests to be rejected by the given circuit breaker while it is open.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := b.allow(); err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil && req.Context().Err() != nil {
+				// Requests that were canceled say nothing about the gateway. Release the probe, if any, without
+				// counting the request for or against the gateway.
+				b.lock.Lock()
+				b.probing = false
+				b.lock.Unlock()
+				return resp, err
+			}
+			b.record(err)
+			return resp, err
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	refused := errors.New("connection refused")
+
+	// Each step issues a request that the gateway answers with the given result, optionally after waiting out the
+	// cooldown, and checks whether the request reached the gateway and whether it was rejected by the breaker.
+	type step struct {
+		result   interface{} // The status code or error with which the gateway answers.
+		wait     bool
+		canceled bool
+		issued   bool
+		rejected bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "opens after consecutive failures", steps: []step{
+			{result: refused, issued: true},
+			{result: refused, issued: true},
+			{result: 200, rejected: true},
+		}},
+		{name: "error responses do not count", steps: []step{
+			{result: refused, issued: true},
+			{result: 500, issued: true},
+			{result: refused, issued: true},
+			{result: 200, issued: true},
+		}},
+		{name: "closes after a successful probe", steps: []step{
+			{result: refused, issued: true},
+			{result: refused, issued: true},
+			{result: 200, wait: true, issued: true},
+			{result: 200, issued: true},
+		}},
+		{name: "reopens after a failed probe", steps: []step{
+			{result: refused, issued: true},
+			{result: refused, issued: true},
+			{result: refused, wait: true, issued: true},
+			{result: 200, rejected: true},
+		}},
+		{name: "canceled probes do not count", steps: []step{
+			{result: refused, issued: true},
+			{result: refused, issued: true},
+			{result: refused, wait: true, canceled: true, issued: true},
+			{result: 200, issued: true},
+			{result: 200, issued: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result interface{}
+			issued := false
+			rt := roundTripper(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				issued = true
+				if err, ok := result.(error); ok {
+					return nil, err
+				}
+				return response(result.(int)), nil
+			}), WithCircuitBreaker(NewCircuitBreaker(2, cooldown)))
+
+			for i, s := range tt.steps {
+				if s.wait {
+					time.Sleep(cooldown)
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				if s.canceled {
+					cancel()
+				}
+				req, err := http.NewRequest("GET", "http://gateway.test/healthz", nil)
+				if !assert.NoError(t, err) {
+					cancel()
+					return
+				}
+
+				result, issued = s.result, false
+				_, err = rt.RoundTrip(req.WithContext(ctx))
+				cancel()
+
+				_, rejected := err.(*CircuitOpenError)
+				assert.Equal(t, s.issued, issued, "step %d: issued", i)
+				assert.Equal(t, s.rejected, rejected, "step %d: rejected", i)
+			}
+		})
+	}
+}
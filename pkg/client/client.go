@@ -1,29 +1,125 @@
 package client
 
+// The gateway's wire types are generated from its OpenAPI spec, which is vendored from github.com/openfaas/faas at the
+// commit given by GATEWAY_SPEC_COMMIT in the Makefile. Run `make gateway_spec` to update it.
+//go:generate go run ../../cmd/gen-client-models -spec spec/spec.openapi.yml -out models_gen.go -package client -types FunctionDeployment,FunctionStatus,FunctionResources,FunctionUsage
+
 import (
 	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 )
 
-// Function represents an OpenFaaS function definition.
+// Function represents an OpenFaaS function: the specification with which it is deployed and, when read from the
+// gateway, its status.
 type Function struct {
-	Service      string            `json:"service"`
-	Network      string            `json:"network"`
-	Image        string            `json:"image"`
-	EnvProcess   string            `json:"envProcess"`
-	EnvVars      map[string]string `json:"envVars"`
-	Labels       map[string]string `json:"labels"`
-	Annotations  map[string]string `json:"annotations"`
-	Secrets      []string          `json:"secrets"`
-	RegistryAuth string            `json:"registryAuth"`
+	Service                string
+	Namespace              string
+	Network                string
+	Image                  string
+	EnvProcess             string
+	EnvVars                map[string]string
+	Labels                 map[string]string
+	Annotations            map[string]string
+	Secrets                []string
+	RegistryAuth           string
+	Constraints            []string
+	Limits                 *FunctionResources
+	Requests               *FunctionResources
+	ReadOnlyRootFilesystem bool
+
+	// The remaining fields describe the function's status. They are reported by the gateway and ignored when creating
+	// or updating functions.
+
+	// Replicas is the number of replicas of the function that the gateway is trying to run.
+	Replicas uint64
+	// AvailableReplicas is the number of replicas of the function that are ready to serve requests.
+	AvailableReplicas uint64
+	// InvocationCount is the number of times the function has been invoked.
+	InvocationCount float64
+	// CreatedAt is the time at which the function was created, if known.
+	CreatedAt *time.Time
+	// Usage is the function's resource usage, if reported by the gateway.
+	Usage *FunctionUsage
+}
+
+// deployment returns the request with which the function is created or updated.
+func (f *Function) deployment() *FunctionDeployment {
+	return &FunctionDeployment{
+		Service:                f.Service,
+		Image:                  f.Image,
+		Namespace:              f.Namespace,
+		EnvProcess:             f.EnvProcess,
+		Constraints:            f.Constraints,
+		EnvVars:                f.EnvVars,
+		Secrets:                f.Secrets,
+		Labels:                 f.Labels,
+		Annotations:            f.Annotations,
+		Limits:                 f.Limits,
+		Requests:               f.Requests,
+		ReadOnlyRootFilesystem: f.ReadOnlyRootFilesystem,
+		RegistryAuth:           f.RegistryAuth,
+		Network:                f.Network,
+	}
+}
+
+// functionFromStatus returns the function described by the given status.
+func functionFromStatus(s *FunctionStatus) Function {
+	return Function{
+		Service:                s.Name,
+		Namespace:              s.Namespace,
+		Image:                  s.Image,
+		EnvProcess:             s.EnvProcess,
+		EnvVars:                s.EnvVars,
+		Labels:                 s.Labels,
+		Annotations:            s.Annotations,
+		Secrets:                s.Secrets,
+		Constraints:            s.Constraints,
+		Limits:                 s.Limits,
+		Requests:               s.Requests,
+		ReadOnlyRootFilesystem: s.ReadOnlyRootFilesystem,
+		Replicas:               uint64(s.Replicas),
+		AvailableReplicas:      uint64(s.AvailableReplicas),
+		InvocationCount:        s.InvocationCount,
+		CreatedAt:              s.CreatedAt,
+		Usage:                  s.Usage,
+	}
+}
+
+// SystemInfo describes an OpenFaaS installation.
+type SystemInfo struct {
+	// Provider describes the provider that the gateway deploys functions with, e.g. faas-netes or faasd.
+	Provider ProviderInfo `json:"provider"`
+	// Version is the version of the gateway.
+	Version VersionInfo `json:"version"`
+	// Arch is the CPU architecture of the gateway.
+	Arch string `json:"arch,omitempty"`
+}
+
+// ProviderInfo describes the provider that a gateway deploys functions with.
+type ProviderInfo struct {
+	// Name is the name of the provider, e.g. faas-netes or faasd.
+	Name string `json:"provider"`
+	// Orchestration is the orchestrator that the provider targets, e.g. kubernetes or containerd.
+	Orchestration string `json:"orchestration"`
+	// Version is the version of the provider.
+	Version VersionInfo `json:"version"`
+}
+
+// VersionInfo describes the version of an OpenFaaS component.
+type VersionInfo struct {
+	Release string `json:"release"`
+	SHA     string `json:"sha"`
 }
 
 // Client is a simple client for the OpenFaaS REST API.
@@ -31,93 +127,350 @@ type Client struct {
 	httpClient    *http.Client
 	baseURL       string
 	authorization string
+	headers       map[string]string
+	middleware    []Middleware
+
+	defaultTimeout time.Duration
+	userAgent      string
+	logger         Logger
+	tracer         Tracer
 }
 
-// ErrNotFound is returned by the client if a resource cannot be found.
-var ErrNotFound = errors.New("not found")
+// Option configures optional client behavior.
+type Option func(c *Client)
 
-// NewClient creates a new OpenFaaS client with the given HTTP client, base URL, and optional credentials.
-func NewClient(c *http.Client, baseURL, username, password string) *Client {
-	authorization := ""
-	if username != "" {
-		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+// NewClient creates a new OpenFaaS client with the given HTTP client, base URL, optional Authorization header value,
+// and optional additional headers to send with every request. Use BasicAuth or BearerAuth to construct the
+// Authorization header value. If the HTTP client is nil, the OpenFaaS client creates a dedicated HTTP client with
+// conservative connection timeouts rather than sharing http.DefaultClient. Use WithDefaultTimeout to bound calls.
+// The base URL may include the path under which the gateway's API is served, e.g. https://example.com/faas when the
+// gateway is exposed by a reverse proxy.
+func NewClient(c *http.Client, baseURL, authorization string, headers map[string]string, opts ...Option) *Client {
+	if c == nil {
+		c = newDefaultHTTPClient()
 	}
-	return &Client{
+	client := &Client{
 		httpClient:    c,
 		baseURL:       baseURL,
 		authorization: authorization,
+		headers:       headers,
+		userAgent:     DefaultUserAgent(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	// Copy the HTTP client so that the caller's client is not affected by the middleware. Responses are always
+	// compressed where the gateway supports it, since function listings for large clusters can be sizable. The
+	// User-Agent is applied last so that requests issued by middleware, e.g. for tokens, identify the client as well.
+	// For the same reason, requests are logged and traced after all other middleware has been applied.
+	middleware := client.middleware
+	if client.logger != nil {
+		middleware = append(middleware, logRequests(client.logger))
 	}
+	if client.tracer != nil {
+		middleware = append(middleware, traceRequests(client.tracer))
+	}
+	httpClient := *c
+	httpClient.Transport = chain(c.Transport, append(middleware, Gzip, setUserAgent(client.userAgent)))
+	client.httpClient = &httpClient
+	return client
 }
 
-func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+// newDefaultHTTPClient creates the HTTP client used by clients that are not given one.
+func newDefaultHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// resolve returns the URL of the given API path, which may include a query string, relative to the gateway's base
+// URL. Unlike plain concatenation, this tolerates base URLs with paths and trailing slashes.
+func (c *Client) resolve(path string) (string, error) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid gateway URL")
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + ref.Path
+	u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + ref.EscapedPath()
+	u.RawQuery = ref.RawQuery
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// BasicAuth returns the Authorization header value for the given username and password.
+func BasicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// BearerAuth returns the Authorization header value for the given bearer token.
+func BearerAuth(token string) string {
+	return "Bearer " + token
+}
+
+// send issues a request to the gateway and returns its response regardless of the response's status code. The given
+// headers are sent in addition to the client's headers. The call is bounded by the client's default timeout unless
+// the context overrides it; the call's deadline remains in effect until the response body is closed.
+func (c *Client) send(ctx context.Context, method, path string, body []byte,
+	headers map[string]string) (*http.Response, error) {
+
+	ctx, cancel := c.callContext(ctx)
+	resp, err := c.sendContext(ctx, method, path, body, headers)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) sendContext(ctx context.Context, method, path string, body []byte,
+	headers map[string]string) (*http.Response, error) {
+
+	u, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 	if c.authorization != "" {
 		req.Header.Set("Authorization", c.authorization)
 	}
 	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		// Errors caused by the context being done are reported as-is.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, connectionError{err}
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	return c.doWithHeaders(ctx, method, path, body, nil)
+}
+
+func (c *Client) doWithHeaders(ctx context.Context, method, path string, body []byte,
+	headers map[string]string) (*http.Response, error) {
+
+	resp, err := c.send(ctx, method, path, body, headers)
 	if err != nil {
 		return nil, err
 	}
 	switch resp.StatusCode {
-	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
 		return resp, nil
 	case http.StatusNotFound:
+		contract.IgnoreClose(resp.Body)
 		return nil, ErrNotFound
 	default:
 		defer contract.IgnoreClose(resp.Body)
 		b, err := ioutil.ReadAll(resp.Body)
 		contract.IgnoreError(err)
-		return nil, errors.Errorf("%d response from server (%s)", resp.StatusCode, string(b))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(b), Method: method, Path: path}
 	}
 }
 
-// CreateFunction creates a new function from the given function specification.
+// withNamespace adds the given namespace to the given path as a query parameter. If namespace is empty, the path is
+// returned as-is, and the gateway uses its default namespace.
+func withNamespace(path, namespace string) string {
+	if namespace == "" {
+		return path
+	}
+	return path + "?namespace=" + url.QueryEscape(namespace)
+}
+
+// CreateFunction creates a new function from the given function specification. The function is created in the
+// specification's namespace, if any.
 func (c *Client) CreateFunction(ctx context.Context, f *Function) error {
-	body, err := json.Marshal(f)
+	body, err := json.Marshal(f.deployment())
 	if err != nil {
 		return err
 	}
 
-	_, err = c.do(ctx, "POST", "/system/functions", body)
-	return err
+	resp, err := c.do(ctx, "POST", withNamespace("/system/functions", f.Namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
 }
 
-// GetFunction gets the function specificiation for the function with the given name.
-func (c *Client) GetFunction(ctx context.Context, name string) (*Function, error) {
-	resp, err := c.do(ctx, "GET", "/system/function/"+url.PathEscape(name), nil)
+// GetFunction gets the function specificiation for the function with the given name. If namespace is empty, the
+// gateway's default namespace is used.
+func (c *Client) GetFunction(ctx context.Context, name, namespace string) (*Function, error) {
+	resp, err := c.do(ctx, "GET", withNamespace("/system/function/"+url.PathEscape(name), namespace), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer contract.IgnoreClose(resp.Body)
 
-	var f Function
-	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+	var status FunctionStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
 		return nil, err
 	}
+	f := functionFromStatus(&status)
 	return &f, nil
 }
 
-// UpdateFunction updates the function with the given specification.
+// ListFunctions lists the functions in the given namespace. If namespace is empty, the gateway's default namespace is
+// used.
+func (c *Client) ListFunctions(ctx context.Context, namespace string) ([]Function, error) {
+	resp, err := c.do(ctx, "GET", withNamespace("/system/functions", namespace), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	var statuses []FunctionStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil, err
+	}
+	functions := make([]Function, len(statuses))
+	for i := range statuses {
+		functions[i] = functionFromStatus(&statuses[i])
+	}
+	return functions, nil
+}
+
+// ListFunctionsWithLabels lists the functions in the given namespace that carry all of the given labels with the
+// given values, e.g. the labels that identify the functions managed by a particular stack. The gateway API does not
+// support label selectors, so functions are filtered by the client.
+func (c *Client) ListFunctionsWithLabels(ctx context.Context, namespace string,
+	labels map[string]string) ([]Function, error) {
+
+	functions, err := c.ListFunctions(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := functions[:0]
+	for _, f := range functions {
+		if hasLabels(f, labels) {
+			matches = append(matches, f)
+		}
+	}
+	return matches, nil
+}
+
+// hasLabels returns true if the given function carries all of the given labels with the given values.
+func hasLabels(f Function, labels map[string]string) bool {
+	for k, v := range labels {
+		if actual, ok := f.Labels[k]; !ok || actual != v {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateFunction updates the function with the given specification. The function is updated in the specification's
+// namespace, if any.
 func (c *Client) UpdateFunction(ctx context.Context, f *Function) error {
-	body, err := json.Marshal(f)
+	body, err := json.Marshal(f.deployment())
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "PUT", withNamespace("/system/functions", f.Namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
+
+// DeleteFunction deletes the function with the given name. If namespace is empty, the gateway's default namespace is
+// used.
+func (c *Client) DeleteFunction(ctx context.Context, name, namespace string) error {
+	// Gateways differ in whether they read the namespace from the query or from the payload, so send both.
+	body, err := json.Marshal(struct {
+		FunctionName string `json:"functionName"`
+		Namespace    string `json:"namespace,omitempty"`
+	}{name, namespace})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "DELETE", withNamespace("/system/functions", namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
+
+// ScaleFunction sets the number of replicas of the function with the given name. Scaling a function to zero replicas
+// leaves the function deployed but idle. If namespace is empty, the gateway's default namespace is used.
+func (c *Client) ScaleFunction(ctx context.Context, name, namespace string, replicas uint64) error {
+	body, err := json.Marshal(struct {
+		ServiceName string `json:"serviceName"`
+		Namespace   string `json:"namespace,omitempty"`
+		Replicas    uint64 `json:"replicas"`
+	}{name, namespace, replicas})
 	if err != nil {
 		return err
 	}
 
-	_, err = c.do(ctx, "PUT", "/system/functions", body)
-	return err
+	resp, err := c.do(ctx, "POST", withNamespace("/system/scale-function/"+url.PathEscape(name), namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
 }
 
-// DeleteFunction deletes the function with the given name.
-func (c *Client) DeleteFunction(ctx context.Context, name string) error {
-	body, err := json.Marshal(map[string]string{"functionName": name})
+// healthzTimeout bounds the time spent checking the health of a gateway. Health checks are cheap, so a gateway that
+// does not respond promptly is treated as unhealthy rather than left to hold up the caller.
+const healthzTimeout = 5 * time.Second
+
+// Healthz checks that the gateway is reachable and healthy. The check fails if the gateway does not respond within a
+// few seconds, or before the given context's deadline if that is sooner.
+func (c *Client) Healthz(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthzTimeout)
+	defer cancel()
+
+	resp, err := c.do(ctx, "GET", "/healthz", nil)
 	if err != nil {
 		return err
 	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
+
+// GetSystemInfo gets information about the OpenFaaS installation behind the gateway.
+func (c *Client) GetSystemInfo(ctx context.Context) (*SystemInfo, error) {
+	resp, err := c.do(ctx, "GET", "/system/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(resp.Body)
 
-	_, err = c.do(ctx, "DELETE", "/system/functions", body)
-	return err
+	var info SystemInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
 }
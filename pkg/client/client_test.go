@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFunctionWireFormat(t *testing.T) {
+	var deployed map[string]interface{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "POST":
+			b, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(b, &deployed))
+			w.WriteHeader(http.StatusAccepted)
+		case "GET":
+			// Function statuses identify functions by name rather than by service.
+			_, err := w.Write([]byte(`{"name":"echo","image":"ghcr.io/openfaas/alpine:latest","namespace":"staging",` +
+				`"limits":{"memory":"128Mi"},"replicas":2,"availableReplicas":1,"invocationCount":1337,` +
+				`"createdAt":"2019-01-01T00:00:00Z","usage":{"cpu":0.01,"totalMemoryBytes":1024}}`))
+			assert.NoError(t, err)
+		}
+	}))
+	defer gateway.Close()
+
+	c := NewClient(nil, gateway.URL, "", nil)
+	err := c.CreateFunction(context.Background(), &Function{
+		Service:     "echo",
+		Namespace:   "staging",
+		Image:       "ghcr.io/openfaas/alpine:latest",
+		Constraints: []string{"node.platform.os == linux"},
+		Limits:      &FunctionResources{Memory: "128Mi"},
+		Replicas:    2,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]interface{}{
+		"service":     "echo",
+		"namespace":   "staging",
+		"image":       "ghcr.io/openfaas/alpine:latest",
+		"constraints": []interface{}{"node.platform.os == linux"},
+		"limits":      map[string]interface{}{"memory": "128Mi"},
+	}, deployed, "status fields must not be sent")
+
+	f, err := c.GetFunction(context.Background(), "echo", "staging")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "echo", f.Service)
+	assert.Equal(t, "staging", f.Namespace)
+	assert.Equal(t, &FunctionResources{Memory: "128Mi"}, f.Limits)
+	assert.Equal(t, uint64(2), f.Replicas)
+	assert.Equal(t, uint64(1), f.AvailableReplicas)
+	assert.Equal(t, float64(1337), f.InvocationCount)
+	if assert.NotNil(t, f.CreatedAt) {
+		assert.Equal(t, 2019, f.CreatedAt.Year())
+	}
+	assert.Equal(t, &FunctionUsage{CPU: 0.01, TotalMemoryBytes: 1024}, f.Usage)
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		path    string
+		url     string
+	}{
+		{baseURL: "http://gateway.test", path: "/system/functions", url: "http://gateway.test/system/functions"},
+		{baseURL: "http://gateway.test/", path: "/system/functions", url: "http://gateway.test/system/functions"},
+		{baseURL: "https://example.com/faas", path: "/healthz", url: "https://example.com/faas/healthz"},
+		{baseURL: "https://example.com/faas/", path: "/healthz", url: "https://example.com/faas/healthz"},
+		{
+			baseURL: "https://example.com/faas",
+			path:    "/system/function/echo?namespace=staging",
+			url:     "https://example.com/faas/system/function/echo?namespace=staging",
+		},
+		{baseURL: "https://example.com/a%2Fb", path: "/healthz", url: "https://example.com/a%2Fb/healthz"},
+	}
+	for _, tt := range tests {
+		u, err := NewClient(nil, tt.baseURL, "", nil).resolve(tt.path)
+		if assert.NoError(t, err, tt.baseURL) {
+			assert.Equal(t, tt.url, u, tt.baseURL)
+		}
+	}
+
+	_, err := NewClient(nil, "http://gateway.test:8080/%zz", "", nil).resolve("/healthz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// CustomResource renders the function as an openfaas.com/v1 Function custom resource in YAML, suitable for managing
+// the function with the OpenFaaS operator, e.g. from a GitOps repository. Status fields are omitted, as are registry
+// credentials, which the operator reads from image pull secrets instead.
+func (f *Function) CustomResource() string {
+	var b bytes.Buffer
+	b.WriteString("apiVersion: openfaas.com/v1\n")
+	b.WriteString("kind: Function\n")
+	b.WriteString("metadata:\n")
+	writeYAMLString(&b, 1, "name", f.Service)
+	writeYAMLString(&b, 1, "namespace", f.Namespace)
+	b.WriteString("spec:\n")
+	writeYAMLString(&b, 1, "name", f.Service)
+	writeYAMLString(&b, 1, "image", f.Image)
+	writeYAMLString(&b, 1, "handler", f.EnvProcess)
+	writeYAMLMap(&b, 1, "environment", f.EnvVars)
+	writeYAMLMap(&b, 1, "labels", f.Labels)
+	writeYAMLMap(&b, 1, "annotations", f.Annotations)
+	writeYAMLList(&b, 1, "constraints", f.Constraints)
+	writeYAMLList(&b, 1, "secrets", f.Secrets)
+	writeYAMLResources(&b, 1, "limits", f.Limits)
+	writeYAMLResources(&b, 1, "requests", f.Requests)
+	if f.ReadOnlyRootFilesystem {
+		b.WriteString("  readOnlyRootFilesystem: true\n")
+	}
+	return b.String()
+}
+
+// The writeYAML functions write YAML mapping entries at the given indentation level. Empty values are omitted.
+// Strings are always written as double-quoted scalars, whose escapes are a superset of Go's, so that no value can be
+// misread as another YAML type.
+
+func writeYAMLString(b *bytes.Buffer, level int, key, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "%*s%s: %s\n", 2*level, "", key, strconv.Quote(value))
+	}
+}
+
+func writeYAMLMap(b *bytes.Buffer, level int, key string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "%*s%s:\n", 2*level, "", key)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%*s%s: %s\n", 2*(level+1), "", strconv.Quote(k), strconv.Quote(m[k]))
+	}
+}
+
+func writeYAMLList(b *bytes.Buffer, level int, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%*s%s:\n", 2*level, "", key)
+	for _, v := range values {
+		fmt.Fprintf(b, "%*s- %s\n", 2*(level+1), "", strconv.Quote(v))
+	}
+}
+
+func writeYAMLResources(b *bytes.Buffer, level int, key string, r *FunctionResources) {
+	if r == nil || (r.Memory == "" && r.CPU == "") {
+		return
+	}
+	fmt.Fprintf(b, "%*s%s:\n", 2*level, "", key)
+	writeYAMLString(b, level+1, "memory", r.Memory)
+	writeYAMLString(b, level+1, "cpu", r.CPU)
+}
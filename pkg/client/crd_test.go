@@ -0,0 +1,70 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestCustomResource(t *testing.T) {
+	f := &Function{
+		Service:                "echo",
+		Namespace:              "staging",
+		Image:                  "functions/alpine:latest",
+		EnvProcess:             "cat",
+		EnvVars:                map[string]string{"write_debug": "true", "LOG_LEVEL": "debug"},
+		Labels:                 map[string]string{"com.openfaas.scale.min": "2"},
+		Constraints:            []string{"node.platform.os == linux"},
+		Secrets:                []string{"api-key"},
+		RegistryAuth:           "dXNlcjpwYXNz",
+		Limits:                 &FunctionResources{Memory: "128Mi"},
+		Requests:               &FunctionResources{},
+		ReadOnlyRootFilesystem: true,
+		Replicas:               3,
+	}
+	assert.Equal(t, `apiVersion: openfaas.com/v1
+kind: Function
+metadata:
+  name: "echo"
+  namespace: "staging"
+spec:
+  name: "echo"
+  image: "functions/alpine:latest"
+  handler: "cat"
+  environment:
+    "LOG_LEVEL": "debug"
+    "write_debug": "true"
+  labels:
+    "com.openfaas.scale.min": "2"
+  constraints:
+    - "node.platform.os == linux"
+  secrets:
+    - "api-key"
+  limits:
+    memory: "128Mi"
+  readOnlyRootFilesystem: true
+`, f.CustomResource())
+}
+
+func TestCustomResourceQuoting(t *testing.T) {
+	// Values that YAML would otherwise read as booleans, numbers, nulls, or structure must survive a round trip.
+	values := []string{"yes", "0x1F", "~", "null", "a: b", "- item", "#comment", "line\nbreak", "tab\there", `q"uote`,
+		"ünïcödé"}
+	env := map[string]string{}
+	for _, v := range values {
+		env[v] = v
+	}
+	f := &Function{Service: "echo", Image: "functions/alpine:latest", EnvVars: env, Secrets: values}
+
+	var cr struct {
+		Spec struct {
+			Environment map[string]string `yaml:"environment"`
+			Secrets     []string          `yaml:"secrets"`
+		} `yaml:"spec"`
+	}
+	if assert.NoError(t, yaml.Unmarshal([]byte(f.CustomResource()), &cr)) {
+		assert.Equal(t, env, cr.Spec.Environment)
+		assert.Equal(t, values, cr.Spec.Secrets)
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by the client if a resource cannot be found.
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned by the client for requests that the gateway rejected with an unexpected status code.
+type APIError struct {
+	// StatusCode is the HTTP status code of the gateway's response.
+	StatusCode int
+	// Body is the body of the gateway's response, which usually describes why the request was rejected.
+	Body string
+	// Method is the HTTP method of the rejected request.
+	Method string
+	// Path is the path of the rejected request, relative to the gateway's base URL.
+	Path string
+}
+
+// Error returns a description of the error that includes the gateway's message.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v %v: %d response from server (%s)", e.Method, e.Path, e.StatusCode,
+		strings.TrimSpace(e.Body))
+}
+
+// connectionError wraps errors for requests that did not receive a response from the gateway.
+type connectionError struct {
+	error
+}
+
+// Cause returns the underlying error.
+func (e connectionError) Cause() error {
+	return e.error
+}
+
+// StatusCode returns the HTTP status code with which the gateway rejected the request that caused the given error.
+// If the error was not caused by an unexpected status code, StatusCode returns false.
+func StatusCode(err error) (int, bool) {
+	if err == ErrNotFound {
+		return http.StatusNotFound, true
+	}
+	if err, ok := err.(*APIError); ok {
+		return err.StatusCode, true
+	}
+	return 0, false
+}
+
+// IsConnectionError returns true if the given error was caused by a failure to communicate with the gateway, e.g. a
+// DNS resolution failure, a refused or reset connection, or a TLS handshake failure.
+func IsConnectionError(err error) bool {
+	_, ok := err.(connectionError)
+	return ok
+}
+
+// IsTransient returns true if the given error was caused by a transient failure, such as the gateway being
+// temporarily overloaded or unavailable or the connection to the gateway being reset.
+func IsTransient(err error) bool {
+	switch err := err.(type) {
+	case *APIError:
+		switch err.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	case connectionError:
+		return isConnectionReset(err.error)
+	}
+	return false
+}
+
+// isConnectionReset returns true if the given error indicates that the connection to the server was reset, refused,
+// or closed before a response was received.
+func isConnectionReset(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	switch err := err.(type) {
+	case *url.Error:
+		return isConnectionReset(err.Err)
+	case *net.OpError:
+		return isConnectionReset(err.Err)
+	case *os.SyscallError:
+		return err.Err == syscall.ECONNRESET || err.Err == syscall.ECONNREFUSED
+	}
+	return false
+}
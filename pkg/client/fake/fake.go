@@ -0,0 +1,190 @@
+// Package fake provides an in-memory implementation of the OpenFaaS functions API for use in tests.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+)
+
+// Client is an in-memory implementation of client.FunctionsAPI. Functions are ready as soon as they are created. The
+// zero value is an empty, healthy gateway.
+type Client struct {
+	// HealthError, if non-nil, is returned by Healthz.
+	HealthError error
+	// DroppedCreateResponses is the number of upcoming calls to CreateFunction that create the function but then fail
+	// with a transient error, as if the gateway's response had been lost.
+	DroppedCreateResponses int
+
+	lock      sync.Mutex
+	functions map[string]client.Function
+}
+
+var _ client.FunctionsAPI = (*Client)(nil)
+
+// NewClient creates a fake client for a gateway that hosts the given functions.
+func NewClient(functions ...client.Function) *Client {
+	c := &Client{}
+	for _, f := range functions {
+		c.put(f)
+	}
+	return c
+}
+
+// key returns the key under which the function with the given name and namespace is stored.
+func key(name, namespace string) string {
+	return namespace + "/" + name
+}
+
+func (c *Client) put(f client.Function) {
+	if c.functions == nil {
+		c.functions = map[string]client.Function{}
+	}
+	c.functions[key(f.Service, f.Namespace)] = f
+}
+
+// Functions returns the functions hosted by the fake gateway, ordered by namespace and name.
+func (c *Client) Functions() []client.Function {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	keys := make([]string, 0, len(c.functions))
+	for k := range c.functions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	functions := make([]client.Function, len(keys))
+	for i, k := range keys {
+		functions[i] = c.functions[k]
+	}
+	return functions
+}
+
+// CreateFunction creates a new function from the given function specification.
+func (c *Client) CreateFunction(ctx context.Context, f *client.Function) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.functions[key(f.Service, f.Namespace)]; ok {
+		return &client.APIError{
+			StatusCode: http.StatusConflict,
+			Body:       fmt.Sprintf("function %v already exists", f.Service),
+			Method:     "POST",
+			Path:       "/system/functions",
+		}
+	}
+
+	created := *f
+	now := time.Now().UTC()
+	created.Replicas, created.AvailableReplicas, created.CreatedAt = 1, 1, &now
+	c.put(created)
+
+	if c.DroppedCreateResponses > 0 {
+		c.DroppedCreateResponses--
+		return &client.APIError{StatusCode: http.StatusBadGateway, Method: "POST", Path: "/system/functions"}
+	}
+	return nil
+}
+
+// GetFunction gets the function with the given name and namespace.
+func (c *Client) GetFunction(ctx context.Context, name, namespace string) (*client.Function, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f, ok := c.functions[key(name, namespace)]
+	if !ok {
+		return nil, client.ErrNotFound
+	}
+	return &f, nil
+}
+
+// ListFunctions lists the functions in the given namespace.
+func (c *Client) ListFunctions(ctx context.Context, namespace string) ([]client.Function, error) {
+	var functions []client.Function
+	for _, f := range c.Functions() {
+		if f.Namespace == namespace {
+			functions = append(functions, f)
+		}
+	}
+	return functions, nil
+}
+
+// ListFunctionsWithLabels lists the functions in the given namespace that carry all of the given labels with the
+// given values.
+func (c *Client) ListFunctionsWithLabels(ctx context.Context, namespace string,
+	labels map[string]string) ([]client.Function, error) {
+
+	functions, err := c.ListFunctions(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var matches []client.Function
+	for _, f := range functions {
+		matched := true
+		for k, v := range labels {
+			if actual, ok := f.Labels[k]; !ok || actual != v {
+				matched = false
+			}
+		}
+		if matched {
+			matches = append(matches, f)
+		}
+	}
+	return matches, nil
+}
+
+// UpdateFunction updates the function with the given specification. The function's status is preserved.
+func (c *Client) UpdateFunction(ctx context.Context, f *client.Function) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	old, ok := c.functions[key(f.Service, f.Namespace)]
+	if !ok {
+		return client.ErrNotFound
+	}
+
+	updated := *f
+	updated.Replicas, updated.AvailableReplicas = old.Replicas, old.AvailableReplicas
+	updated.InvocationCount, updated.CreatedAt = old.InvocationCount, old.CreatedAt
+	c.put(updated)
+	return nil
+}
+
+// DeleteFunction deletes the function with the given name and namespace.
+func (c *Client) DeleteFunction(ctx context.Context, name, namespace string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	k := key(name, namespace)
+	if _, ok := c.functions[k]; !ok {
+		return client.ErrNotFound
+	}
+	delete(c.functions, k)
+	return nil
+}
+
+// ScaleFunction sets the number of replicas of the function with the given name and namespace. Scaling takes effect
+// immediately.
+func (c *Client) ScaleFunction(ctx context.Context, name, namespace string, replicas uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f, ok := c.functions[key(name, namespace)]
+	if !ok {
+		return client.ErrNotFound
+	}
+	f.Replicas, f.AvailableReplicas = replicas, replicas
+	c.put(f)
+	return nil
+}
+
+// Healthz returns HealthError.
+func (c *Client) Healthz(ctx context.Context) error {
+	return c.HealthError
+}
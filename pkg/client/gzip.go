@@ -0,0 +1,66 @@
+package client
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// Gzip is middleware that requests gzip-compressed responses and transparently decompresses them. http.Transport
+// does this on its own, but only if the request does not already carry an Accept-Encoding header and only for
+// responses that pass through it directly; this middleware works with any underlying transport.
+func Gzip(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept-Encoding") != "" || req.Method == "HEAD" {
+			return next.RoundTrip(req)
+		}
+
+		gzipReq := new(http.Request)
+		*gzipReq = *req
+		gzipReq.Header = make(http.Header, len(req.Header)+1)
+		for k, v := range req.Header {
+			gzipReq.Header[k] = v
+		}
+		gzipReq.Header.Set("Accept-Encoding", "gzip")
+
+		resp, err := next.RoundTrip(gzipReq)
+		if err != nil || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			return resp, err
+		}
+
+		resp.Body = &gzipReader{body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	})
+}
+
+// gzipReader lazily decompresses a gzip-compressed response body. Decompression is deferred until the first read so
+// that errors in the gzip header are reported to the reader rather than by RoundTrip.
+type gzipReader struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (r *gzipReader) Read(p []byte) (int, error) {
+	if r.zr == nil && r.err == nil {
+		r.zr, r.err = gzip.NewReader(r.body)
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.zr.Read(p)
+}
+
+func (r *gzipReader) Close() error {
+	if r.zr != nil {
+		contract.IgnoreClose(r.zr)
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gzipped returns the gzip compression of the given string.
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestGzip(t *testing.T) {
+	var acceptEncoding string
+	rt := Gzip(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		acceptEncoding = req.Header.Get("Accept-Encoding")
+		resp := response(http.StatusOK)
+		switch req.URL.Path {
+		case "/compressed":
+			body := gzipped(t, `{"name":"echo"}`)
+			resp.Header.Set("Content-Encoding", "GZIP")
+			resp.Header.Set("Content-Length", "42")
+			resp.ContentLength = int64(len(body))
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		case "/corrupt":
+			resp.Header.Set("Content-Encoding", "gzip")
+			resp.Body = ioutil.NopCloser(bytes.NewReader([]byte("not gzip")))
+		default:
+			resp.Body = ioutil.NopCloser(bytes.NewReader([]byte(`{"name":"echo"}`)))
+		}
+		return resp, nil
+	}))
+
+	get := func(path string) (*http.Response, string, error) {
+		req, err := http.NewRequest("GET", "http://gateway.test"+path, nil)
+		if !assert.NoError(t, err) {
+			return nil, "", err
+		}
+		resp, err := rt.RoundTrip(req)
+		if !assert.NoError(t, err) {
+			return nil, "", err
+		}
+		defer func() { assert.NoError(t, resp.Body.Close()) }()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Empty(t, req.Header.Get("Accept-Encoding"), "the caller's request must not be modified")
+		return resp, string(body), err
+	}
+
+	resp, body, err := get("/compressed")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "gzip", acceptEncoding)
+		assert.Equal(t, `{"name":"echo"}`, body)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Empty(t, resp.Header.Get("Content-Length"))
+		assert.Equal(t, int64(-1), resp.ContentLength)
+		assert.True(t, resp.Uncompressed)
+	}
+
+	resp, body, err = get("/plain")
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"name":"echo"}`, body)
+		assert.False(t, resp.Uncompressed)
+	}
+
+	// Errors in the gzip header are reported when the body is read.
+	_, _, err = get("/corrupt")
+	assert.Error(t, err)
+
+	// Requests that choose their own encoding are passed through as-is.
+	req, err := http.NewRequest("GET", "http://gateway.test/plain", nil)
+	if assert.NoError(t, err) {
+		req.Header.Set("Accept-Encoding", "identity")
+		resp, err = rt.RoundTrip(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "identity", acceptEncoding)
+			assert.NoError(t, resp.Body.Close())
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// tokenExpiryMargin is the amount of time before a token's expiry at which the token is refreshed. This keeps
+// requests that are issued just before a token expires from being rejected.
+const tokenExpiryMargin = time.Minute
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeIDToken           = "urn:ietf:params:oauth:token-type:id_token"
+	tokenTypeAccessToken       = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// IAMCredentials describes the credentials used to authenticate with a gateway that is protected by OpenFaaS IAM.
+// Either an OIDC token that was obtained out of band or an issuer, client ID, and client secret with which to obtain
+// one must be specified.
+type IAMCredentials struct {
+	// Issuer is the URL of the OIDC issuer that issues tokens for the client.
+	Issuer string
+	// ClientID is the ID of the OIDC client.
+	ClientID string
+	// ClientSecret is the secret of the OIDC client.
+	ClientSecret string
+	// IDToken is a pre-obtained OIDC token. If set, the issuer, client ID, and client secret are ignored.
+	IDToken string
+}
+
+// Validate checks that the credentials are complete.
+func (c IAMCredentials) Validate() error {
+	if c.IDToken != "" {
+		return nil
+	}
+	var missing []string
+	if c.Issuer == "" {
+		missing = append(missing, "issuer")
+	}
+	if c.ClientID == "" {
+		missing = append(missing, "client ID")
+	}
+	if c.ClientSecret == "" {
+		missing = append(missing, "client secret")
+	}
+	if len(missing) != 0 {
+		return errors.Errorf("OpenFaaS IAM credentials require an OIDC token or an issuer, client ID, and client "+
+			"secret (missing %v)", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// IAMTransport is an http.RoundTripper that authenticates requests to a gateway protected by OpenFaaS IAM. The
+// transport exchanges an OIDC token for a gateway token and refreshes the gateway token as it nears expiry, so
+// long-running deployments are not interrupted by expired credentials.
+type IAMTransport struct {
+	// Base is the transport used to issue requests, including token requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// GatewayURL is the base URL of the gateway.
+	GatewayURL string
+	// Credentials are the credentials used to obtain gateway tokens.
+	Credentials IAMCredentials
+	// Headers are additional headers to send with token exchange requests to the gateway.
+	Headers map[string]string
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse is the response of an OAuth 2.0 token endpoint.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// RoundTrip authenticates and issues the given request.
+func (t *IAMTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.gatewayToken(req.Context())
+	if err != nil {
+		return nil, errors.Wrap(err, "obtaining OpenFaaS IAM token")
+	}
+
+	// RoundTrippers must not modify the request, so authenticate a copy.
+	authReq := new(http.Request)
+	*authReq = *req
+	authReq.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		authReq.Header[k] = v
+	}
+	authReq.Header.Set("Authorization", BearerAuth(token))
+
+	return t.base().RoundTrip(authReq)
+}
+
+func (t *IAMTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// gatewayToken returns a valid gateway token, obtaining a new one if the current token is missing or near expiry.
+func (t *IAMTransport) gatewayToken(ctx context.Context) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(t.expiry)) {
+		return t.token, nil
+	}
+
+	subjectToken, subjectTokenType := t.Credentials.IDToken, tokenTypeIDToken
+	if subjectToken == "" {
+		resp, err := t.clientCredentialsToken(ctx)
+		if err != nil {
+			return "", err
+		}
+		subjectToken = resp.IDToken
+		if subjectToken == "" {
+			subjectToken, subjectTokenType = resp.AccessToken, tokenTypeAccessToken
+		}
+	}
+
+	resp, err := t.requestToken(ctx, strings.TrimSuffix(t.GatewayURL, "/")+"/oauth/token", t.Headers, url.Values{
+		"grant_type":         {grantTypeTokenExchange},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {subjectTokenType},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "exchanging OIDC token")
+	}
+
+	t.token, t.expiry = resp.AccessToken, time.Time{}
+	if resp.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return t.token, nil
+}
+
+// clientCredentialsToken obtains an OIDC token from the issuer using the client credentials grant.
+func (t *IAMTransport) clientCredentialsToken(ctx context.Context) (*tokenResponse, error) {
+	tokenEndpoint, err := t.tokenEndpoint(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "discovering the token endpoint of %v", t.Credentials.Issuer)
+	}
+	resp, err := t.requestToken(ctx, tokenEndpoint, nil, url.Values{
+		"grant_type":    {grantTypeClientCredentials},
+		"client_id":     {t.Credentials.ClientID},
+		"client_secret": {t.Credentials.ClientSecret},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "obtaining an OIDC token from %v", t.Credentials.Issuer)
+	}
+	return resp, nil
+}
+
+// tokenEndpoint discovers the token endpoint of the OIDC issuer.
+func (t *IAMTransport) tokenEndpoint(ctx context.Context) (string, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(t.Credentials.Issuer, "/")+
+		"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err = t.doJSON(req.WithContext(ctx), &config); err != nil {
+		return "", err
+	}
+	if config.TokenEndpoint == "" {
+		return "", errors.New("the issuer's configuration does not specify a token endpoint")
+	}
+	return config.TokenEndpoint, nil
+}
+
+// requestToken requests a token from the given token endpoint.
+func (t *IAMTransport) requestToken(ctx context.Context, endpoint string, headers map[string]string,
+	form url.Values) (*tokenResponse, error) {
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp tokenResponse
+	if err = t.doJSON(req.WithContext(ctx), &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" && resp.IDToken == "" {
+		return nil, errors.New("the token response did not contain a token")
+	}
+	return &resp, nil
+}
+
+// doJSON issues the given request using the base transport and decodes its JSON response into v.
+func (t *IAMTransport) doJSON(req *http.Request, v interface{}) error {
+	resp, err := (&http.Client{Transport: t.base()}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(resp.Body)
+		contract.IgnoreError(err)
+		return errors.Errorf("%d response from server (%s)", resp.StatusCode, string(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
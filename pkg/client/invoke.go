@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// InvocationResult is the response of a synchronous function invocation.
+type InvocationResult struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Header holds the headers of the response.
+	Header http.Header
+	// Body is the body of the response.
+	Body []byte
+}
+
+// Invoke synchronously invokes the function with the given name with the given body and headers and returns the
+// function's response. Responses are returned regardless of their status code, so callers must check the result's
+// StatusCode to determine whether the invocation succeeded. Functions outside the gateway's default namespace are
+// named "name.namespace".
+func (c *Client) Invoke(ctx context.Context, name string, body []byte,
+	headers map[string]string) (*InvocationResult, error) {
+
+	resp, err := c.send(ctx, "POST", "/function/"+url.PathEscape(name), body, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &InvocationResult{StatusCode: resp.StatusCode, Header: resp.Header, Body: b}, nil
+}
+
+// InvokeAsync queues an asynchronous invocation of the function with the given name and returns the invocation's
+// call ID. The function receives the given body and headers. If callbackURL is non-empty, the gateway posts the
+// function's response to that URL once the invocation completes.
+func (c *Client) InvokeAsync(ctx context.Context, name string, body []byte, headers map[string]string,
+	callbackURL string) (string, error) {
+
+	if callbackURL != "" {
+		withCallback := make(map[string]string, len(headers)+1)
+		for k, v := range headers {
+			withCallback[k] = v
+		}
+		withCallback["X-Callback-Url"] = callbackURL
+		headers = withCallback
+	}
+
+	resp, err := c.doWithHeaders(ctx, "POST", "/async-function/"+url.PathEscape(name), body, headers)
+	if err != nil {
+		return "", err
+	}
+	contract.IgnoreClose(resp.Body)
+	return resp.Header.Get("X-Call-Id"), nil
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestLog describes a single request issued by the client. Each retry of a request is logged separately.
+type RequestLog struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request.
+	Path string
+	// StatusCode is the status code of the response, or zero if no response was received.
+	StatusCode int
+	// Duration is the time between issuing the request and receiving the response's headers.
+	Duration time.Duration
+	// Err is the error that prevented a response from being received, if any.
+	Err error
+}
+
+// Logger is notified of each request that the client issues.
+type Logger interface {
+	LogRequest(entry RequestLog)
+}
+
+// LoggerFunc adapts an ordinary function to the Logger interface.
+type LoggerFunc func(entry RequestLog)
+
+// LogRequest calls f(entry).
+func (f LoggerFunc) LogRequest(entry RequestLog) {
+	f(entry)
+}
+
+// WithLogger causes the client to notify the given logger of each request that it issues.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// logRequests returns middleware that notifies the given logger of each request.
+func logRequests(logger Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			entry := RequestLog{Method: req.Method, Path: req.URL.Path, Duration: time.Since(start), Err: err}
+			if resp != nil {
+				entry.StatusCode = resp.StatusCode
+			}
+			logger.LogRequest(entry)
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LogMessage is a single line of a function's logs.
+type LogMessage struct {
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Namespace is the namespace of the function.
+	Namespace string `json:"namespace,omitempty"`
+	// Instance is the name of the replica that produced the line.
+	Instance string `json:"instance"`
+	// Timestamp is the time at which the line was produced.
+	Timestamp time.Time `json:"timestamp"`
+	// Text is the text of the line.
+	Text string `json:"text"`
+}
+
+// LogStream iterates over the lines of a function's logs. Its usage mirrors bufio.Scanner: call Next to advance to
+// each line and Message to retrieve it, then check Err once Next returns false. The stream must be closed once it is
+// no longer needed.
+type LogStream struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	message LogMessage
+	err     error
+}
+
+// Next advances the stream to the next line. It returns false once the stream ends or an error occurs.
+func (s *LogStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	var msg LogMessage
+	if err := s.decoder.Decode(&msg); err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	s.message = msg
+	return true
+}
+
+// Message returns the current line.
+func (s *LogStream) Message() LogMessage {
+	return s.message
+}
+
+// Err returns the error, if any, that ended the stream.
+func (s *LogStream) Err() error {
+	return s.err
+}
+
+// Close closes the stream.
+func (s *LogStream) Close() error {
+	return s.body.Close()
+}
+
+// StreamLogs streams the logs of the function with the given name. If namespace is empty, the gateway's default
+// namespace is used. If since is non-zero, only lines produced after that time are returned. If follow is true, the
+// stream remains open and returns new lines as they are produced until the context is done or the stream is closed.
+// Followed streams are not subject to the client's default timeout.
+func (c *Client) StreamLogs(ctx context.Context, name, namespace string, since time.Time,
+	follow bool) (*LogStream, error) {
+
+	query := url.Values{"name": {name}, "follow": {strconv.FormatBool(follow)}}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	if follow {
+		ctx = WithTimeout(ctx, 0)
+	}
+	resp, err := c.do(ctx, "GET", "/system/logs?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LogStream{
+		body:    resp.Body,
+		decoder: json.NewDecoder(bufio.NewReader(resp.Body)),
+	}, nil
+}
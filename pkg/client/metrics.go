@@ -0,0 +1,135 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// invocationMetric is the name of the Prometheus counter in which the gateway records function invocations.
+const invocationMetric = "gateway_function_invocation_total"
+
+// FunctionMetrics summarizes the invocations of a function as recorded by the gateway.
+type FunctionMetrics struct {
+	// Invocations maps HTTP status codes to the number of invocations that returned them.
+	Invocations map[string]float64
+	// Successes is the number of invocations that returned a 2xx or 3xx status code.
+	Successes float64
+	// Errors is the number of invocations that returned any other status code.
+	Errors float64
+}
+
+// Total returns the total number of invocations.
+func (m *FunctionMetrics) Total() float64 {
+	return m.Successes + m.Errors
+}
+
+// GetFunctionMetrics gets the invocation metrics of the function with the given name from the Prometheus metrics
+// that the gateway exposes at /metrics. If namespace is empty, invocations of functions with the given name in any
+// namespace are counted. Functions that have not been invoked since the gateway started have no metrics, which is
+// reported as zero invocations rather than as an error.
+func (c *Client) GetFunctionMetrics(ctx context.Context, name, namespace string) (*FunctionMetrics, error) {
+	resp, err := c.do(ctx, "GET", "/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	metrics := &FunctionMetrics{Invocations: map[string]float64{}}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, invocationMetric+"{") {
+			continue
+		}
+		labels, value, err := parseSample(line[len(invocationMetric):])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing metrics sample %q", line)
+		}
+		if !matchesFunction(labels["function_name"], name, namespace) {
+			continue
+		}
+
+		code := labels["code"]
+		metrics.Invocations[code] += value
+		if strings.HasPrefix(code, "2") || strings.HasPrefix(code, "3") {
+			metrics.Successes += value
+		} else {
+			metrics.Errors += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// matchesFunction returns true if the given function_name label value refers to the function with the given name and
+// namespace. The gateway labels functions as "name.namespace".
+func matchesFunction(label, name, namespace string) bool {
+	if namespace != "" {
+		return label == name+"."+namespace
+	}
+	return label == name || strings.HasPrefix(label, name+".")
+}
+
+// parseSample parses the labels and value of a Prometheus text format sample whose metric name has been removed, e.g.
+// `{code="200",function_name="echo.openfaas-fn"} 42`. Any timestamp that follows the value is ignored.
+func parseSample(s string) (map[string]string, float64, error) {
+	labels := map[string]string{}
+	s = strings.TrimPrefix(s, "{")
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if strings.HasPrefix(s, "}") {
+			s = s[1:]
+			break
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq == -1 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, 0, errors.New("malformed label")
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+
+		var value strings.Builder
+		closed := false
+		for i := 0; i < len(s); i++ {
+			switch ch := s[i]; {
+			case ch == '\\' && i+1 < len(s):
+				i++
+				if s[i] == 'n' {
+					value.WriteByte('\n')
+				} else {
+					value.WriteByte(s[i])
+				}
+			case ch == '"':
+				s, closed = s[i+1:], true
+			default:
+				value.WriteByte(ch)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, 0, errors.New("unterminated label value")
+		}
+		labels[key] = value.String()
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, 0, errors.New("missing value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, 0, err
+	}
+	return labels, value, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSample(t *testing.T) {
+	tests := []struct {
+		sample string
+		labels map[string]string
+		value  float64
+		err    bool
+	}{
+		{
+			sample: `{code="200",function_name="echo.openfaas-fn"} 42`,
+			labels: map[string]string{"code": "200", "function_name": "echo.openfaas-fn"},
+			value:  42,
+		},
+		{
+			sample: `{code="500", function_name="echo" } 1.5e+01 1560000000000`,
+			labels: map[string]string{"code": "500", "function_name": "echo"},
+			value:  15,
+		},
+		{
+			sample: `{path="a \"quoted\"\nvalue",} 1`,
+			labels: map[string]string{"path": "a \"quoted\"\nvalue"},
+			value:  1,
+		},
+		{sample: `{} 0`, labels: map[string]string{}, value: 0},
+		{sample: `{code=200} 1`, err: true},
+		{sample: `{code="200} 1`, err: true},
+		{sample: `{code="200"}`, err: true},
+		{sample: `{code="200"} NaNa`, err: true},
+	}
+	for _, tt := range tests {
+		labels, value, err := parseSample(tt.sample)
+		if tt.err {
+			assert.Error(t, err, tt.sample)
+			continue
+		}
+		if assert.NoError(t, err, tt.sample) {
+			assert.Equal(t, tt.labels, labels, tt.sample)
+			assert.Equal(t, tt.value, value, tt.sample)
+		}
+	}
+}
+
+func TestMatchesFunction(t *testing.T) {
+	tests := []struct {
+		label     string
+		name      string
+		namespace string
+		matches   bool
+	}{
+		{label: "echo.openfaas-fn", name: "echo", namespace: "openfaas-fn", matches: true},
+		{label: "echo.staging", name: "echo", namespace: "openfaas-fn", matches: false},
+		{label: "echo", name: "echo", namespace: "openfaas-fn", matches: false},
+		{label: "echo.staging", name: "echo", matches: true},
+		{label: "echo", name: "echo", matches: true},
+		{label: "echo-v2.staging", name: "echo", matches: false},
+		{label: "echo2", name: "echo", matches: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.matches, matchesFunction(tt.label, tt.name, tt.namespace), "%+v", tt)
+	}
+}
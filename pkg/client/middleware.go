@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the RoundTripper that issues a client's requests with additional behavior, such as
+// authentication, logging, retries, tracing, or rate limiting. Middleware is composed into a chain in which each
+// middleware calls the next, so that each concern can be implemented and tested independently.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to the http.RoundTripper interface.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware adds the given middleware to the client's chain. Middleware is applied in the order in which it is
+// added: the first middleware sees each request first and each response last.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// chain wraps the given RoundTripper with the given middleware. If base is nil, http.DefaultTransport is used.
+func chain(base http.RoundTripper, middleware []Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		base = middleware[i](base)
+	}
+	return base
+}
@@ -0,0 +1,86 @@
+// Code generated by gen-client-models from spec/spec.openapi.yml. DO NOT EDIT.
+
+package client
+
+import "time"
+
+// FunctionDeployment is the FunctionDeployment schema of the gateway API.
+type FunctionDeployment struct {
+	// Name of deployed function
+	Service string `json:"service"`
+	// Docker image in accessible registry
+	Image string `json:"image"`
+	// Namespace to deploy function to. When omitted, the default namespace is used, typically this is `openfaas-fn` but
+	// is configured by the provider.
+	Namespace string `json:"namespace,omitempty"`
+	// Process for watchdog to fork, i.e. the command to start the function process. This value configures the
+	// `fprocess` env variable.
+	EnvProcess  string   `json:"envProcess,omitempty"`
+	Constraints []string `json:"constraints,omitempty"`
+	// Overrides to environmental variables
+	EnvVars map[string]string `json:"envVars,omitempty"`
+	Secrets []string          `json:"secrets,omitempty"`
+	// A map of labels for making scheduling or routing decisions
+	Labels map[string]string `json:"labels,omitempty"`
+	// A map of annotations for management, orchestration, events and build tasks
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Limits      *FunctionResources `json:"limits,omitempty"`
+	Requests    *FunctionResources `json:"requests,omitempty"`
+	// Make the root filesystem of the function read-only
+	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
+	// Deprecated: Private registry base64-encoded basic auth (as present in ~/.docker/config.json) Use a Kubernetes
+	// Secret with registry-auth secret type to provide this value instead. This value is completely ignored.
+	RegistryAuth string `json:"registryAuth,omitempty"`
+	// Deprecated: Network, usually func_functions for Swarm. This value is completely ignored.
+	Network string `json:"network,omitempty"`
+}
+
+// FunctionStatus is the FunctionStatus schema of the gateway API.
+type FunctionStatus struct {
+	// The name of the function
+	Name string `json:"name"`
+	// The fully qualified docker image name of the function
+	Image string `json:"image"`
+	// The namespace of the function
+	Namespace string `json:"namespace,omitempty"`
+	// Process for watchdog to fork
+	EnvProcess string `json:"envProcess,omitempty"`
+	// environment variables for the function runtime
+	EnvVars     map[string]string `json:"envVars,omitempty"`
+	Constraints []string          `json:"constraints,omitempty"`
+	Secrets     []string          `json:"secrets,omitempty"`
+	// A map of labels for making scheduling or routing decisions
+	Labels map[string]string `json:"labels,omitempty"`
+	// A map of annotations for management, orchestration, events and build tasks
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Limits      *FunctionResources `json:"limits,omitempty"`
+	Requests    *FunctionResources `json:"requests,omitempty"`
+	// removes write-access from the root filesystem mount-point.
+	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
+	// The amount of invocations for the specified function
+	InvocationCount float64 `json:"invocationCount,omitempty"`
+	// Desired amount of replicas
+	Replicas float64 `json:"replicas,omitempty"`
+	// The current available amount of replicas
+	AvailableReplicas float64 `json:"availableReplicas,omitempty"`
+	// CreatedAt is the time read back from the faas backend's data store for when the function or its container was
+	// created.
+	CreatedAt *time.Time     `json:"createdAt,omitempty"`
+	Usage     *FunctionUsage `json:"usage,omitempty"`
+}
+
+// FunctionResources is the FunctionResources schema of the gateway API.
+type FunctionResources struct {
+	// The amount of memory that is allocated for the function
+	Memory string `json:"memory,omitempty"`
+	// The amount of cpu that is allocated for the function
+	CPU string `json:"cpu,omitempty"`
+}
+
+// FunctionUsage is the FunctionUsage schema of the gateway API.
+type FunctionUsage struct {
+	// CPU is the increase in CPU usage since the last measurement equivalent to Kubernetes' concept of millicores.
+	CPU float64 `json:"cpu,omitempty"`
+	// TotalMemoryBytes is the total memory usage in bytes.
+	TotalMemoryBytes float64 `json:"totalMemoryBytes,omitempty"`
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that limits the rate at which requests are made to a gateway. Requests that exceed the
+// limit are queued rather than rejected: each request reserves a token, and waits until the bucket would have held
+// that token. A single limiter may be shared by many clients so that they are throttled together.
+type RateLimiter struct {
+	rate  float64 // The number of tokens added to the bucket per second.
+	burst float64 // The capacity of the bucket.
+
+	lock   sync.Mutex
+	tokens float64   // The number of tokens in the bucket as of last. Negative if tokens have been reserved.
+	last   time.Time // The time at which tokens was last updated.
+}
+
+// NewRateLimiter creates a rate limiter that allows the given number of requests per second on average and bursts of
+// up to the given number of requests. A burst smaller than one is treated as one.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve reserves a token and returns the time to wait before the token is available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (l *RateLimiter) cancel() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.tokens++
+}
+
+// Wait blocks until a request is permitted or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// WithRateLimiter causes each of the client's requests, including retries, to wait for the given rate limiter.
+func WithRateLimiter(l *RateLimiter) Option {
+	return WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := l.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	})
+}
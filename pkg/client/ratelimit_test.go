@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		requests int
+		delay    time.Duration // The delay before the last request is permitted.
+	}{
+		{name: "first request", rate: 10, burst: 1, requests: 1, delay: 0},
+		{name: "within burst", rate: 10, burst: 3, requests: 3, delay: 0},
+		{name: "beyond burst", rate: 10, burst: 1, requests: 2, delay: 100 * time.Millisecond},
+		{name: "queued", rate: 10, burst: 2, requests: 5, delay: 300 * time.Millisecond},
+		{name: "minimum burst", rate: 10, burst: 0, requests: 2, delay: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		l := NewRateLimiter(tt.rate, tt.burst)
+		var delay time.Duration
+		for i := 0; i < tt.requests; i++ {
+			delay = l.reserve()
+		}
+		// Tokens accrue while the test runs, so the delay may be slightly shorter than expected.
+		assert.True(t, delay <= tt.delay && delay >= tt.delay-10*time.Millisecond, "%v: delay %v, expected %v",
+			tt.name, delay, tt.delay)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	assert.NoError(t, l.Wait(context.Background()))
+
+	// A request that gives up waiting returns its token, so it does not delay the requests behind it.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx))
+	assert.True(t, l.reserve() <= time.Second)
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// RetryPolicy controls how the client retries idempotent requests that fail due to a transient error. Requests are
+// retried with exponential backoff, except that a Retry-After header sent with a 429 or 503 response takes precedence.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a request is retried. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry. Each subsequent retry doubles the delay.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries, including delays requested by the gateway. Zero imposes no cap.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable retry policy for most uses.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 5, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+
+// Backoff returns the jittered exponential backoff before the given retry attempt (starting at zero). Callers that
+// retry non-idempotent operations themselves may use it to wait between attempts.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// delay returns the delay before retrying the request that received the given response, if any, on the given retry
+// attempt.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	throttled := resp != nil &&
+		(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable)
+	if throttled {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay
+		}
+	}
+	return p.Backoff(attempt)
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// isIdempotent returns true if requests with the given method can safely be issued more than once.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus returns true if a response with the given status code indicates a transient failure.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// WithRetries causes the client to retry idempotent requests that fail due to a transient error according to the
+// given policy. By default, requests are not retried.
+func WithRetries(policy RetryPolicy) Option {
+	return WithMiddleware(Retry(policy))
+}
+
+// Retry returns middleware that retries idempotent requests that fail due to a transient error according to the
+// given policy. Responses that are retried are closed; the final response or error is returned as-is.
+func Retry(policy RetryPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// Requests whose bodies cannot be replayed are issued once.
+			maxRetries := policy.MaxRetries
+			if !isIdempotent(req.Method) || (req.Body != nil && req.GetBody == nil) {
+				maxRetries = 0
+			}
+
+			for attempt := 0; ; attempt++ {
+				resp, err := next.RoundTrip(req)
+				if attempt == maxRetries {
+					return resp, err
+				}
+				switch {
+				case err != nil && !isConnectionReset(err):
+					return resp, err
+				case err == nil && !isRetryableStatus(resp.StatusCode):
+					return resp, err
+				}
+
+				delay := policy.delay(attempt, resp)
+				if resp != nil {
+					contract.IgnoreClose(resp.Body)
+				}
+				select {
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				case <-time.After(delay):
+				}
+
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					retry := *req
+					retry.Body = body
+					req = &retry
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripper returns the given transport wrapped with the middleware added by the given options.
+func roundTripper(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return chain(base, c.middleware)
+}
+
+// response returns a response with the given status code and headers.
+func response(status int, header ...string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(nil))}
+	for i := 0; i+1 < len(header); i += 2 {
+		resp.Header.Set(header[i], header[i+1])
+	}
+	return resp
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "0", delay: 0, ok: true},
+		{value: "3", delay: 3 * time.Second, ok: true},
+		{value: "-1", ok: false},
+		{value: "soon", ok: false},
+		{value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), delay: 0, ok: true},
+	}
+	for _, tt := range tests {
+		delay, ok := retryAfter(tt.value)
+		assert.Equal(t, tt.ok, ok, "Retry-After: %q", tt.value)
+		assert.Equal(t, tt.delay, delay, "Retry-After: %q", tt.value)
+	}
+
+	// HTTP dates have a resolution of one second, so the delay until a future date is approximate.
+	delay, ok := retryAfter(time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, delay > 8*time.Second && delay <= 10*time.Second, "delay %v", delay)
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	reset := errors.New("connection reset")
+
+	tests := []struct {
+		name     string
+		method   string
+		results  []interface{} // The status code or error of each attempt. The last result repeats.
+		attempts int
+		status   int
+		err      bool
+	}{
+		{name: "success", method: "GET", results: []interface{}{200}, attempts: 1, status: 200},
+		{name: "transient status", method: "GET", results: []interface{}{503, 200}, attempts: 2, status: 200},
+		{name: "connection reset", method: "GET", results: []interface{}{io.EOF, 200}, attempts: 2, status: 200},
+		{name: "exhausted", method: "GET", results: []interface{}{502}, attempts: 3, status: 502},
+		{name: "permanent status", method: "GET", results: []interface{}{404}, attempts: 1, status: 404},
+		{name: "permanent error", method: "GET", results: []interface{}{reset}, attempts: 1, err: true},
+		{name: "idempotent PUT", method: "PUT", results: []interface{}{504, 200}, attempts: 2, status: 200},
+		{name: "non-idempotent POST", method: "POST", results: []interface{}{503, 200}, attempts: 1, status: 503},
+		{name: "non-idempotent reset", method: "POST", results: []interface{}{io.EOF, 200}, attempts: 1, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			rt := roundTripper(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				result := tt.results[len(tt.results)-1]
+				if attempts < len(tt.results) {
+					result = tt.results[attempts]
+				}
+				attempts++
+				if err, ok := result.(error); ok {
+					return nil, err
+				}
+				return response(result.(int)), nil
+			}), WithRetries(policy))
+
+			req, err := http.NewRequest(tt.method, "http://gateway.test/system/functions", nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp, err := rt.RoundTrip(req)
+			assert.Equal(t, tt.attempts, attempts)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name     string
+		body     func() io.Reader
+		attempts int
+	}{
+		// http.NewRequest sets GetBody for the standard in-memory readers, so their bodies can be replayed.
+		{name: "replayable", body: func() io.Reader { return bytes.NewReader([]byte(`{"service":"echo"}`)) },
+			attempts: 3},
+		{name: "not replayable", body: func() io.Reader { return ioutil.NopCloser(bytes.NewBufferString("{}")) },
+			attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			rt := roundTripper(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				b, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				bodies = append(bodies, string(b))
+				return response(http.StatusServiceUnavailable), nil
+			}), WithRetries(policy))
+
+			req, err := http.NewRequest("PUT", "http://gateway.test/system/functions", tt.body())
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = rt.RoundTrip(req)
+			assert.NoError(t, err)
+			if assert.Len(t, bodies, tt.attempts) {
+				for _, b := range bodies {
+					assert.Equal(t, bodies[0], b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		min, max time.Duration
+	}{
+		{name: "backoff", resp: response(502), min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "retry after", resp: response(429, "Retry-After", "2"), min: 2 * time.Second, max: 2 * time.Second},
+		{name: "capped retry after", resp: response(503, "Retry-After", "60"), min: 5 * time.Second,
+			max: 5 * time.Second},
+		{name: "ignored retry after", resp: response(502, "Retry-After", "2"), min: 50 * time.Millisecond,
+			max: 100 * time.Millisecond},
+		{name: "no response", min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		delay := policy.delay(0, tt.resp)
+		assert.True(t, delay >= tt.min && delay <= tt.max, "%v: delay %v not in [%v, %v]", tt.name, delay,
+			tt.min, tt.max)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// Secret represents an OpenFaaS secret.
+type Secret struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Value     string `json:"value,omitempty"`
+	// RawValue is the secret's binary value. It is used in place of Value for secrets that are not valid UTF-8.
+	RawValue []byte `json:"rawValue,omitempty"`
+}
+
+// secretsPath returns the path of the secrets endpoint for the given namespace. If namespace is empty, the gateway's
+// default namespace is used.
+func secretsPath(namespace string) string {
+	return withNamespace("/system/secrets", namespace)
+}
+
+// CreateSecret creates a new secret.
+func (c *Client) CreateSecret(ctx context.Context, s *Secret) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "POST", secretsPath(s.Namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
+
+// GetSecretNames returns the names of the secrets in the given namespace. The gateway never returns the values of
+// secrets.
+func (c *Client) GetSecretNames(ctx context.Context, namespace string) ([]string, error) {
+	resp, err := c.do(ctx, "GET", secretsPath(namespace), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer contract.IgnoreClose(resp.Body)
+
+	var secrets []Secret
+	if err := json.NewDecoder(resp.Body).Decode(&secrets); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(secrets))
+	for i, s := range secrets {
+		names[i] = s.Name
+	}
+	return names, nil
+}
+
+// UpdateSecret updates the value of an existing secret.
+func (c *Client) UpdateSecret(ctx context.Context, s *Secret) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "PUT", secretsPath(s.Namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
+
+// DeleteSecret deletes the secret with the given name from the given namespace. If namespace is empty, the
+// gateway's default namespace is used.
+func (c *Client) DeleteSecret(ctx context.Context, name, namespace string) error {
+	body, err := json.Marshal(&Secret{Name: name, Namespace: namespace})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, "DELETE", secretsPath(namespace), body)
+	if err != nil {
+		return err
+	}
+	contract.IgnoreClose(resp.Body)
+	return nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// timeoutKey is the context key under which per-call timeout overrides are stored.
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of the given context that overrides the client's default timeout for calls made with
+// it. A zero timeout disables the default timeout, which is useful for long-running calls such as following logs.
+// Deadlines already carried by the context continue to apply.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// WithDefaultTimeout bounds the time spent on each call made by the client, including reading the response body,
+// unless the call's context overrides it with WithTimeout. By default, calls are bounded only by their contexts.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.defaultTimeout = timeout
+	}
+}
+
+// callContext derives the context for a single call from the given context and the client's default timeout.
+func (c *Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := c.defaultTimeout
+	if override, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// cancelOnClose releases a call's context once the response body that belongs to the call is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// functionRoutes are the prefixes of the gateway routes that address a single function by name.
+var functionRoutes = []string{"/system/function/", "/system/scale-function/", "/async-function/", "/function/"}
+
+// RequestSpan describes a request for which a Tracer records a span.
+type RequestSpan struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Route is the path of the request with any function name replaced by a placeholder, e.g.
+	// /system/function/{name}, which keeps span names low-cardinality.
+	Route string
+	// Host is the host of the gateway.
+	Host string
+	// Function is the name of the function that the request addresses, if any.
+	Function string
+}
+
+// Tracer records a span for each request that the client issues. The client does not depend on any particular
+// tracing library; programs plug in an implementation for the library that they export traces with.
+type Tracer interface {
+	// StartSpan starts a span for the given request as a child of any span in ctx. It may add headers to header to
+	// propagate the span's context to the gateway. It returns the context with which to issue the request and a
+	// function that ends the span, which is called with the response's status code, or zero and the error that
+	// prevented a response from being received.
+	StartSpan(ctx context.Context, span RequestSpan,
+		header http.Header) (context.Context, func(statusCode int, err error))
+}
+
+// WithTracer causes the client to record a span with the given tracer for each request that it issues. By default,
+// requests are not traced.
+func WithTracer(tracer Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// traceRequests returns middleware that records a span for each request with the given tracer.
+func traceRequests(tracer Tracer) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			route, function := requestRoute(req.URL)
+			span := RequestSpan{Method: req.Method, Route: route, Host: req.URL.Host, Function: function}
+
+			// Copy the headers so that propagating the span's context does not modify the caller's request.
+			header := make(http.Header, len(req.Header)+2)
+			for k, v := range req.Header {
+				header[k] = v
+			}
+			ctx, end := tracer.StartSpan(req.Context(), span, header)
+			tracedReq := req.WithContext(ctx)
+			tracedReq.Header = header
+
+			resp, err := next.RoundTrip(tracedReq)
+			if err != nil {
+				end(0, err)
+				return resp, err
+			}
+			end(resp.StatusCode, nil)
+			return resp, nil
+		})
+	}
+}
+
+// requestRoute returns the route of the given request URL with any function name replaced by a placeholder, which
+// keeps span names low-cardinality, and the name of the function that the request addresses, if any. Routes are
+// matched anywhere in the path so that gateways served under a path prefix are handled.
+func requestRoute(u *url.URL) (string, string) {
+	for _, route := range functionRoutes {
+		if i := strings.Index(u.Path, route); i != -1 {
+			name := u.Path[i+len(route):]
+			if j := strings.IndexByte(name, '/'); j != -1 {
+				name = name[:j]
+			}
+			return u.Path[:i+len(route)] + "{name}", name
+		}
+	}
+	if strings.HasSuffix(u.Path, "/system/logs") {
+		return u.Path, u.Query().Get("name")
+	}
+	return u.Path, ""
+}
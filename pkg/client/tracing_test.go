@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTracer records the spans that it starts and how they ended.
+type recordingTracer struct {
+	spans    []RequestSpan
+	statuses []int
+	errs     []error
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, span RequestSpan,
+	header http.Header) (context.Context, func(int, error)) {
+
+	t.spans = append(t.spans, span)
+	header.Set("Traceparent", "test-span")
+	return ctx, func(statusCode int, err error) {
+		t.statuses = append(t.statuses, statusCode)
+		t.errs = append(t.errs, err)
+	}
+}
+
+func TestTraceRequests(t *testing.T) {
+	refused := errors.New("connection refused")
+
+	tracer := &recordingTracer{}
+	var propagated string
+	rt := traceRequests(tracer)(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		propagated = req.Header.Get("Traceparent")
+		if req.Method == "DELETE" {
+			return nil, refused
+		}
+		return response(http.StatusNotFound), nil
+	}))
+
+	req, err := http.NewRequest("GET", "http://gateway.test/faas/system/function/echo?namespace=staging", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = rt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-span", propagated)
+	assert.Empty(t, req.Header.Get("Traceparent"), "the caller's request must not be modified")
+
+	req, err = http.NewRequest("DELETE", "http://gateway.test/system/functions", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = rt.RoundTrip(req)
+	assert.Equal(t, refused, err)
+
+	assert.Equal(t, []RequestSpan{
+		{Method: "GET", Route: "/faas/system/function/{name}", Host: "gateway.test", Function: "echo"},
+		{Method: "DELETE", Route: "/system/functions", Host: "gateway.test"},
+	}, tracer.spans)
+	assert.Equal(t, []int{http.StatusNotFound, 0}, tracer.statuses)
+	assert.Equal(t, []error{nil, refused}, tracer.errs)
+}
+
+func TestRequestRoute(t *testing.T) {
+	tests := []struct {
+		url      string
+		route    string
+		function string
+	}{
+		{url: "http://gateway.test/system/functions", route: "/system/functions"},
+		{url: "http://gateway.test/system/function/echo", route: "/system/function/{name}", function: "echo"},
+		{url: "http://gateway.test/function/echo/sub/path", route: "/function/{name}", function: "echo"},
+		{url: "http://gateway.test/faas/async-function/echo", route: "/faas/async-function/{name}", function: "echo"},
+		{url: "http://gateway.test/system/logs?name=echo&follow=true", route: "/system/logs", function: "echo"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		route, function := requestRoute(u)
+		assert.Equal(t, tt.route, route, tt.url)
+		assert.Equal(t, tt.function, function, tt.url)
+	}
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/version"
+)
+
+// DefaultUserAgent returns the User-Agent header value that the client sends unless configured otherwise.
+func DefaultUserAgent() string {
+	v := version.Version
+	if v == "" {
+		v = "dev"
+	}
+	return "pulumi-openfaas-client/" + v
+}
+
+// WithUserAgent sets the User-Agent header value sent with each request, e.g. so that a program that embeds the
+// client can identify itself and its version to gateway operators. Headers passed to NewClient take precedence.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// setUserAgent returns middleware that sets the User-Agent header of requests that do not already specify one.
+func setUserAgent(userAgent string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("User-Agent") != "" {
+				return next.RoundTrip(req)
+			}
+
+			uaReq := new(http.Request)
+			*uaReq = *req
+			uaReq.Header = make(http.Header, len(req.Header)+1)
+			for k, v := range req.Header {
+				uaReq.Header[k] = v
+			}
+			uaReq.Header.Set("User-Agent", userAgent)
+			return next.RoundTrip(uaReq)
+		})
+	}
+}
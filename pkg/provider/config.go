@@ -0,0 +1,343 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
+	"google.golang.org/grpc/codes"
+)
+
+// faasConfigNamespace is the prefix of the provider's configuration keys.
+const faasConfigNamespace = "openfaas:config:"
+
+// providerConfig is the provider's configuration. Each field corresponds to a key in the openfaas:config namespace.
+type providerConfig struct {
+	Endpoint            string             `pulumi:"endpoint"`
+	Username            string             `pulumi:"username,optional"`
+	Password            string             `pulumi:"password,optional"`
+	Token               string             `pulumi:"token,optional"`
+	OIDCIssuer          string             `pulumi:"oidcIssuer,optional"`
+	OIDCClientID        string             `pulumi:"oidcClientId,optional"`
+	OIDCClientSecret    string             `pulumi:"oidcClientSecret,optional"`
+	OIDCToken           string             `pulumi:"oidcToken,optional"`
+	TLSSkipVerify       bool               `pulumi:"tlsSkipVerify,optional"`
+	CACert              string             `pulumi:"caCert,optional"`
+	ClientCert          string             `pulumi:"clientCert,optional"`
+	ClientKey           string             `pulumi:"clientKey,optional"`
+	ProxyURL            string             `pulumi:"proxyUrl,optional"`
+	CredentialCommand   string             `pulumi:"credentialCommand,optional"`
+	CredentialArgs      []string           `pulumi:"credentialArgs,optional"`
+	RefreshCredentials  bool               `pulumi:"refreshCredentials,optional"`
+	Headers             map[string]string  `pulumi:"headers,optional"`
+	Gateways            map[string]gateway `pulumi:"gateways,optional"`
+	UserAgentSuffix     string             `pulumi:"userAgentSuffix,optional"`
+	Namespace           string             `pulumi:"namespace,optional"`
+	DefaultLabels       map[string]string  `pulumi:"defaultLabels,optional"`
+	DefaultAnnotations  map[string]string  `pulumi:"defaultAnnotations,optional"`
+	Parallelism         int                `pulumi:"parallelism,optional,min=0"`
+	MaxRetries          *int               `pulumi:"maxRetries,optional,min=0"`
+	RequestsPerSecond   float64            `pulumi:"requestsPerSecond,optional,min=0"`
+	Burst               int                `pulumi:"burst,optional,min=0"`
+	SkipHealthCheck     bool               `pulumi:"skipHealthCheck,optional"`
+	Offline             bool               `pulumi:"offline,optional"`
+	MaxIdleConns        *int               `pulumi:"maxIdleConns,optional,min=0"`
+	MaxIdleConnsPerHost int                `pulumi:"maxIdleConnsPerHost,optional,min=0"`
+	IdleConnTimeout     *float64           `pulumi:"idleConnTimeout,optional,min=0"`
+	HTTP2               *bool              `pulumi:"http2,optional"`
+	ConnectTimeout      float64            `pulumi:"connectTimeout,optional,min=0"`
+	RequestTimeout      float64            `pulumi:"requestTimeout,optional,min=0"`
+	OperationTimeout    float64            `pulumi:"operationTimeout,optional,min=0"`
+	LenientPropertyKeys bool               `pulumi:"lenientPropertyKeys,optional"`
+	CoerceStrings       bool               `pulumi:"coerceStrings,optional"`
+}
+
+const (
+	// defaultMaxIdleConns is the default maximum number of idle connections. This matches http.DefaultTransport.
+	defaultMaxIdleConns = 100
+	// defaultIdleConnTimeout is the default time after which idle connections are closed. This matches
+	// http.DefaultTransport.
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// configDescriptions describes the provider's configuration keys. These descriptions are shown to the user when
+// required keys are missing.
+var configDescriptions = map[string]string{
+	"endpoint":            "the endpoint of the OpenFaaS API gateway, including any path under which it is served",
+	"username":            "the username to use when authenticating with the OpenFaaS API gateway",
+	"password":            "the password to use when authenticating with the OpenFaaS API gateway",
+	"token":               "a bearer token to use in place of a username and password when authenticating",
+	"oidcIssuer":          "the URL of the OIDC issuer used to obtain tokens for OpenFaaS IAM",
+	"oidcClientId":        "the ID of the OIDC client used to obtain tokens for OpenFaaS IAM",
+	"oidcClientSecret":    "the secret of the OIDC client used to obtain tokens for OpenFaaS IAM",
+	"oidcToken":           "a pre-obtained OIDC token to exchange for OpenFaaS IAM tokens",
+	"tlsSkipVerify":       "whether or not to disable TLS verification when connecting to the OpenFaaS API gateway",
+	"caCert":              "a PEM-encoded CA certificate (or the path to a file containing one) to trust",
+	"clientCert":          "a PEM-encoded client certificate (or the path to a file containing one) for mutual TLS",
+	"clientKey":           "the PEM-encoded private key (or the path to a file containing it) for clientCert",
+	"proxyUrl":            "the URL of the proxy to use when connecting to the gateway (defaults to HTTPS_PROXY)",
+	"credentialCommand":   "a command that prints the credentials to use when authenticating with the gateway",
+	"credentialArgs":      "the arguments to pass to credentialCommand",
+	"refreshCredentials":  "whether or not to re-resolve dynamic credentials before each request",
+	"headers":             "additional HTTP headers to send with every request to the gateway",
+	"gateways":            "named gateway profiles that functions may select in place of the configured gateway",
+	"namespace":           "the namespace to deploy functions to if a function does not specify one",
+	"defaultLabels":       "labels to apply to every function managed by the provider",
+	"defaultAnnotations":  "annotations to apply to every function managed by the provider",
+	"parallelism":         "the maximum number of concurrent calls to the OpenFaaS API gateway (0 for unlimited)",
+	"requestsPerSecond":   "the maximum average number of requests per second to send to the gateway (0 for unlimited)",
+	"burst":               "the maximum number of requests to send to the gateway in a burst above requestsPerSecond",
+	"maxRetries":          "the maximum number of times to retry gateway calls that fail with transient errors",
+	"maxIdleConns":        "the maximum number of idle connections to keep open across all gateways (0 for unlimited)",
+	"maxIdleConnsPerHost": "the maximum number of idle connections to keep open per gateway",
+	"idleConnTimeout":     "the time in seconds after which idle connections are closed (0 for never)",
+	"http2":               "whether or not to use HTTP/2 when the gateway supports it",
+	"connectTimeout":      "the maximum time in seconds to spend connecting to the gateway (0 for unlimited)",
+	"requestTimeout":      "the maximum time in seconds to spend on a single request to the gateway (0 for unlimited)",
+	"operationTimeout":    "the default maximum time in seconds to spend on a resource operation (0 for unlimited)",
+	"offline":             "whether or not to avoid contacting the gateway, e.g. to preview in air-gapped environments",
+	"userAgentSuffix":     "a suffix to append to the User-Agent header sent with requests to the gateway",
+	"skipHealthCheck":     "whether or not to skip checking that the OpenFaaS API gateway is reachable",
+	"lenientPropertyKeys": "whether or not to accept function properties whose names differ only in case and " +
+		"separators, e.g. env_vars for envVars",
+	"coerceStrings": "whether or not to accept strings such as \"true\" or \"3\" for boolean and numeric function " +
+		"properties",
+}
+
+// configEnvVars maps configuration keys to the environment variables that are used as fallbacks when the keys are
+// unset. The variable names match those used by faas-cli.
+var configEnvVars = map[string]string{
+	"endpoint": "OPENFAAS_URL",
+	"username": "OPENFAAS_USERNAME",
+	"password": "OPENFAAS_PASSWORD",
+	"token":    "OPENFAAS_TOKEN",
+}
+
+// withEnvFallbacks returns a copy of the given configuration variables with unset keys filled in from their fallback
+// environment variables, if any.
+func withEnvFallbacks(vars map[string]string) map[string]string {
+	result := make(map[string]string, len(vars))
+	for k, v := range vars {
+		result[k] = v
+	}
+	for name, env := range configEnvVars {
+		key := faasConfigNamespace + name
+		if _, ok := result[key]; ok {
+			continue
+		}
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// credentialsFromEnv returns true if the gateway credentials for the given configuration variables are taken from
+// environment variables, i.e. if no credentials are configured explicitly.
+func credentialsFromEnv(vars map[string]string) bool {
+	for _, name := range []string{"username", "password", "token"} {
+		if _, ok := vars[faasConfigNamespace+name]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// secretConfigKeys is the set of configuration keys whose values are secret. The values of these keys are never
+// logged or included in error messages. The provider's configuration is not written into resource state, but the
+// gateway fields of the same names are, so those fields are tagged as secret.
+var secretConfigKeys = map[string]bool{
+	"password":         true,
+	"token":            true,
+	"oidcClientSecret": true,
+	"oidcToken":        true,
+	"clientKey":        true,
+	"headers":          true,
+}
+
+// unwrapSecret returns the plaintext of the given configuration value. Secret configuration values may be passed to
+// the provider in their serialized form, i.e. as a JSON object that carries the secret signature and the plaintext.
+func unwrapSecret(value string) string {
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		return value
+	}
+	var secret map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &secret); err != nil || secret[resource.SigKey] != resource.SecretSig {
+		return value
+	}
+	if plaintext, ok := secret["value"].(string); ok {
+		return plaintext
+	}
+	return value
+}
+
+// redactConfig returns a copy of the given configuration variables that is safe to log: the values of secret keys
+// are replaced with a placeholder, as are the values of the secret fields of gateway profiles.
+func redactConfig(vars map[string]string) map[string]string {
+	result := make(map[string]string, len(vars))
+	for k, v := range vars {
+		switch name := strings.TrimPrefix(k, faasConfigNamespace); {
+		case secretConfigKeys[name]:
+			v = "[secret]"
+		case name == "gateways":
+			v = redactGateways(v)
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// redactGateways returns a copy of the given gateway profiles, in JSON, with the values of their secret fields
+// replaced with a placeholder. Profiles that cannot be parsed are replaced in their entirety.
+func redactGateways(value string) string {
+	var profiles map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(unwrapSecret(value)), &profiles); err != nil {
+		return "[secret]"
+	}
+	fields, err := structFields(reflect.TypeOf(gateway{}))
+	if err != nil {
+		return "[secret]"
+	}
+	for _, profile := range profiles {
+		for _, f := range fields {
+			if _, ok := profile[f.desc.name]; ok && f.desc.secret {
+				profile[f.desc.name] = "[secret]"
+			}
+		}
+	}
+	b, err := json.Marshal(profiles)
+	if err != nil {
+		return "[secret]"
+	}
+	return string(b)
+}
+
+// configValue converts the string value of a configuration variable to a property value of the given schema type.
+// Configuration variables are always passed to the provider as strings, so non-string values are parsed. Values that
+// cannot be parsed are returned as strings so that the schema check reports them.
+func configValue(value string, schema reflect.Type) resource.PropertyValue {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if schema == durationType || isBytes(schema) || schema == assetType || schema == archiveType {
+		return resource.NewStringProperty(value)
+	}
+
+	switch schema.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return resource.NewBoolProperty(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return resource.NewNumberProperty(n)
+		}
+	case reflect.Map, reflect.Slice, reflect.Struct:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return resource.NewPropertyValue(v)
+		}
+	}
+	return resource.NewStringProperty(value)
+}
+
+// configProperties converts the given configuration variables to properties according to the given schema.
+// Variables that do not correspond to a field of the schema are ignored.
+func configProperties(vars map[string]string, schema interface{}) (resource.PropertyMap, error) {
+	fields, err := structFields(reflect.TypeOf(schema))
+	if err != nil {
+		return nil, err
+	}
+	props := resource.PropertyMap{}
+	for _, f := range fields {
+		if v, ok := vars[faasConfigNamespace+f.desc.name]; ok {
+			props[resource.PropertyKey(f.desc.name)] = configValue(unwrapSecret(v), f.typ)
+		}
+	}
+	return props, nil
+}
+
+// decodeConfig decodes the provider's configuration from the given variables, falling back to environment variables
+// for unset keys where applicable. All missing and invalid keys are reported at once.
+func decodeConfig(vars map[string]string) (*providerConfig, error) {
+	props, err := configProperties(withEnvFallbacks(vars), providerConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{recordMissing: true}
+	if err = c.checkProperty("", resource.NewObjectProperty(props), reflect.TypeOf(providerConfig{})); err != nil {
+		return nil, err
+	}
+
+	var cfg providerConfig
+	if len(c.failures) == 0 && len(c.missing) == 0 {
+		if err = decodeProperties(props, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.failures) == 0 && len(c.missing) == 0 {
+		return &cfg, nil
+	}
+
+	var invalid []string
+	for _, f := range c.failures {
+		invalid = append(invalid, fmt.Sprintf("%v%v: %v", faasConfigNamespace, f.Property, f.Reason))
+	}
+
+	if len(c.missing) == 0 {
+		return nil, rpcerror.Newf(codes.InvalidArgument, "invalid configuration: %v", strings.Join(invalid, "; "))
+	}
+
+	msg := "required configuration keys were missing"
+	if len(invalid) != 0 {
+		msg = fmt.Sprintf("%v; invalid configuration: %v", msg, strings.Join(invalid, "; "))
+	}
+
+	missingKeys := make([]*pulumirpc.ConfigureErrorMissingKeys_MissingKey, len(c.missing))
+	for i, name := range c.missing {
+		description := configDescriptions[name]
+		if env, ok := configEnvVars[name]; ok {
+			description = fmt.Sprintf("%v (may also be set via the %v environment variable)", description, env)
+		}
+		missingKeys[i] = &pulumirpc.ConfigureErrorMissingKeys_MissingKey{
+			Name:        faasConfigNamespace + name,
+			Description: description,
+		}
+	}
+
+	// Clients of our RPC endpoint will be looking for this detail in order to figure out
+	// which keys need descriptive error messages.
+	return nil, rpcerror.WithDetails(rpcerror.New(codes.InvalidArgument, msg), &pulumirpc.ConfigureErrorMissingKeys{
+		MissingKeys: missingKeys,
+	})
+}
+
+// seconds converts a number of seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,132 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// credentialHelperTimeout bounds the time spent running a credential helper.
+const credentialHelperTimeout = 30 * time.Second
+
+// helperCredentials are the credentials printed by a credential helper. A helper prints either a JSON object with
+// these fields or a bare token.
+type helperCredentials struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// runCredentialHelper runs the credential helper for the given gateway and returns the credentials that it prints.
+// The helper is passed the gateway's endpoint in the OPENFAAS_URL environment variable.
+func runCredentialHelper(ctx context.Context, g gateway) (*helperCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, g.CredentialCommand, g.CredentialArgs...)
+	cmd.Env = append(os.Environ(), "OPENFAAS_URL="+g.Endpoint)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.Errorf("%v: %v", err, msg)
+		}
+		return nil, errors.Wrapf(err, "running credential helper %v", g.CredentialCommand)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 {
+		return nil, errors.Errorf("credential helper %v did not print any credentials", g.CredentialCommand)
+	}
+	if output[0] != '{' {
+		return &helperCredentials{Token: string(output)}, nil
+	}
+
+	var creds helperCredentials
+	if err := json.Unmarshal(output, &creds); err != nil {
+		return nil, errors.Wrapf(err, "decoding the output of credential helper %v", g.CredentialCommand)
+	}
+	if creds.Token == "" && creds.Username == "" {
+		return nil, errors.Errorf("credential helper %v did not print a token or username", g.CredentialCommand)
+	}
+	return &creds, nil
+}
+
+// withHelperCredentials returns a copy of the given gateway whose credentials are replaced with those printed by its
+// credential helper, if it has one.
+func withHelperCredentials(ctx context.Context, g gateway) (gateway, error) {
+	if g.CredentialCommand == "" {
+		return g, nil
+	}
+	creds, err := runCredentialHelper(ctx, g)
+	if err != nil {
+		return gateway{}, err
+	}
+	g.Token, g.Username, g.Password = creds.Token, creds.Username, creds.Password
+	return g, nil
+}
+
+// resolveCredentials returns a copy of the given gateway with fresh credentials from its dynamic credential sources:
+// the environment, if the gateway's credentials were taken from environment variables, and its credential helper, if
+// it has one.
+func resolveCredentials(ctx context.Context, g gateway) (gateway, error) {
+	if g.envCredentials {
+		g.Token = os.Getenv(configEnvVars["token"])
+		g.Username = os.Getenv(configEnvVars["username"])
+		g.Password = os.Getenv(configEnvVars["password"])
+	}
+	return withHelperCredentials(ctx, g)
+}
+
+// credentialTransport is an http.RoundTripper that re-resolves a gateway's credentials before each request so that
+// long-running deployments pick up rotated credentials.
+type credentialTransport struct {
+	base    http.RoundTripper
+	gateway gateway
+}
+
+// RoundTrip authenticates and issues the given request.
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	g, err := resolveCredentials(req.Context(), t.gateway)
+	if err != nil {
+		return nil, err
+	}
+	authorization, err := gatewayAuthorization(g)
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the request, so authenticate a copy.
+	authReq := new(http.Request)
+	*authReq = *req
+	authReq.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		authReq.Header[k] = v
+	}
+	if authorization != "" {
+		authReq.Header.Set("Authorization", authorization)
+	}
+
+	return t.base.RoundTrip(authReq)
+}
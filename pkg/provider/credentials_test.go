@@ -0,0 +1,59 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCredentialHelper(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string // A shell script that plays the part of the credential helper.
+		creds  helperCredentials
+		err    string
+	}{
+		{name: "bare token", script: `echo s3cr3t`, creds: helperCredentials{Token: "s3cr3t"}},
+		{name: "token object", script: `echo '{"token": "s3cr3t"}'`, creds: helperCredentials{Token: "s3cr3t"}},
+		{name: "basic auth object", script: `echo '{"username": "admin", "password": "hunter2"}'`,
+			creds: helperCredentials{Username: "admin", Password: "hunter2"}},
+		{name: "surrounding whitespace", script: `printf '\n  s3cr3t  \n'`, creds: helperCredentials{Token: "s3cr3t"}},
+		{name: "endpoint", script: `echo "$OPENFAAS_URL"`, creds: helperCredentials{Token: "http://gateway.test:8080"}},
+		{name: "no output", script: `true`, err: "did not print any credentials"},
+		{name: "no credentials", script: `echo '{"password": "hunter2"}'`, err: "did not print a token or username"},
+		{name: "malformed object", script: `echo '{"token": '`, err: "decoding the output"},
+		{name: "failure", script: `echo denied >&2; exit 1`, err: "denied"},
+	}
+	for _, tt := range tests {
+		g := gateway{
+			Endpoint:          "http://gateway.test:8080",
+			CredentialCommand: "sh",
+			CredentialArgs:    []string{"-c", tt.script},
+		}
+		creds, err := runCredentialHelper(context.Background(), g)
+		if tt.err != "" {
+			if assert.Error(t, err, tt.name) {
+				assert.Contains(t, err.Error(), tt.err, tt.name)
+			}
+			continue
+		}
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.creds, *creds, tt.name)
+		}
+	}
+}
@@ -1,9 +1,18 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"github.com/pkg/errors"
@@ -18,6 +27,36 @@ func typeMismatch(path, expected string, actual resource.PropertyValue) *pulumir
 	}
 }
 
+// numberOutOfRange returns a failure if the given number cannot be represented exactly by a value of the given numeric
+// type: integer types require integral numbers within their range, and float32 requires numbers within its range.
+func numberOutOfRange(path string, n float64, t reflect.Type) *pulumirpc.CheckFailure {
+	fail := func(format string, args ...interface{}) *pulumirpc.CheckFailure {
+		return &pulumirpc.CheckFailure{Property: path, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n != math.Trunc(n) || math.IsInf(n, 0) {
+			return fail("expected an integer, received %v", n)
+		}
+		if n < math.MinInt64 || n >= -math.MinInt64 || reflect.Zero(t).OverflowInt(int64(n)) {
+			return fail("expected a value that fits in an %v, received %v", t.Kind(), n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n != math.Trunc(n) || math.IsInf(n, 0) {
+			return fail("expected an integer, received %v", n)
+		}
+		if n < 0 || n >= 1<<64 || reflect.Zero(t).OverflowUint(uint64(n)) {
+			return fail("expected a value that fits in a %v, received %v", t.Kind(), n)
+		}
+	case reflect.Float32:
+		if !math.IsInf(n, 0) && math.Abs(n) > math.MaxFloat32 {
+			return fail("expected a value that fits in a float32, received %v", n)
+		}
+	}
+	return nil
+}
+
 func missingRequiredProperty(path, key string) *pulumirpc.CheckFailure {
 	return &pulumirpc.CheckFailure{
 		Property: path,
@@ -29,10 +68,175 @@ func failureError(f *pulumirpc.CheckFailure) error {
 	return errors.Errorf("%v: %v", f.Property, f.Reason)
 }
 
+// PropertyMarshaler is implemented by types that encode themselves as property values rather than being encoded
+// according to their structure. Encoded values are not addressable, so MarshalProperty should have a value receiver.
+type PropertyMarshaler interface {
+	MarshalProperty() (resource.PropertyValue, error)
+}
+
+// PropertyUnmarshaler is implemented by types that decode themselves from property values rather than being decoded
+// according to their structure. An error returned by UnmarshalProperty is reported as a check failure.
+type PropertyUnmarshaler interface {
+	UnmarshalProperty(v resource.PropertyValue) error
+}
+
+var (
+	propertyMarshalerType   = reflect.TypeOf((*PropertyMarshaler)(nil)).Elem()
+	propertyUnmarshalerType = reflect.TypeOf((*PropertyUnmarshaler)(nil)).Elem()
+)
+
+// isUnmarshaler returns true if pointers to values of the given type implement PropertyUnmarshaler.
+func isUnmarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(propertyUnmarshalerType)
+}
+
+// unmarshalProperty decodes the given value into a new value of the given type, which must satisfy isUnmarshaler.
+func unmarshalProperty(path string, v resource.PropertyValue, t reflect.Type) (reflect.Value, *pulumirpc.CheckFailure) {
+	dest := reflect.New(t)
+	if err := dest.Interface().(PropertyUnmarshaler).UnmarshalProperty(v); err != nil {
+		return reflect.Value{}, &pulumirpc.CheckFailure{Property: path, Reason: err.Error()}
+	}
+	return dest.Elem(), nil
+}
+
+// durationType is the type of time.Duration fields, whose values are represented as duration strings such as "30s".
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parseDuration parses the duration string held by the given value.
+func parseDuration(path string, v resource.PropertyValue) (time.Duration, *pulumirpc.CheckFailure) {
+	if !v.IsString() {
+		return 0, typeMismatch(path, "duration", v)
+	}
+	d, err := time.ParseDuration(v.StringValue())
+	if err != nil {
+		return 0, &pulumirpc.CheckFailure{
+			Property: path,
+			Reason:   fmt.Sprintf("expected a duration such as \"30s\" or \"2m\", received %q", v.StringValue()),
+		}
+	}
+	return d, nil
+}
+
+// rawMessageType is the type of json.RawMessage fields, whose values are arbitrary property values that are carried
+// as JSON.
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// isBytes returns true if the given type is a byte slice other than json.RawMessage. Byte slices are represented as
+// base64-encoded strings.
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && t != rawMessageType
+}
+
+// decodeBytes decodes the base64-encoded string held by the given value.
+func decodeBytes(path string, v resource.PropertyValue) ([]byte, *pulumirpc.CheckFailure) {
+	if !v.IsString() {
+		return nil, typeMismatch(path, "base64-encoded string", v)
+	}
+	b, err := base64.StdEncoding.DecodeString(v.StringValue())
+	if err != nil {
+		return nil, &pulumirpc.CheckFailure{
+			Property: path, Reason: fmt.Sprintf("invalid base64-encoded string: %v", err),
+		}
+	}
+	return b, nil
+}
+
+// marshalRawMessage encodes the given value as JSON.
+func marshalRawMessage(path string, v resource.PropertyValue) (json.RawMessage, *pulumirpc.CheckFailure) {
+	b, err := json.Marshal(v.Mappable())
+	if err != nil {
+		return nil, &pulumirpc.CheckFailure{
+			Property: path, Reason: fmt.Sprintf("value cannot be encoded as JSON: %v", err),
+		}
+	}
+	return b, nil
+}
+
+// assetType and archiveType are the types of resource.Asset and resource.Archive fields, whose values are Pulumi assets
+// and archives. Strings that hold a path or URI are also accepted in place of either.
+var (
+	assetType   = reflect.TypeOf(resource.Asset{})
+	archiveType = reflect.TypeOf(resource.Archive{})
+)
+
+// isURI returns true if the given string is a URI rather than a path. Single-letter schemes are Windows drive letters.
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && len(u.Scheme) > 1
+}
+
+// decodeAsset decodes the asset held by the given value, which may instead be a string that holds a path or URI.
+func decodeAsset(path string, v resource.PropertyValue) (*resource.Asset, *pulumirpc.CheckFailure) {
+	if v.IsAsset() {
+		return v.AssetValue(), nil
+	}
+	if !v.IsString() {
+		return nil, typeMismatch(path, "asset", v)
+	}
+	var a *resource.Asset
+	var err error
+	if isURI(v.StringValue()) {
+		a, err = resource.NewURIAsset(v.StringValue())
+	} else {
+		a, err = resource.NewPathAsset(v.StringValue())
+	}
+	if err != nil {
+		return nil, &pulumirpc.CheckFailure{Property: path, Reason: fmt.Sprintf("invalid asset: %v", err)}
+	}
+	return a, nil
+}
+
+// decodeArchive decodes the archive held by the given value, which may instead be a string that holds a path or URI.
+func decodeArchive(path string, v resource.PropertyValue) (*resource.Archive, *pulumirpc.CheckFailure) {
+	if v.IsArchive() {
+		return v.ArchiveValue(), nil
+	}
+	if !v.IsString() {
+		return nil, typeMismatch(path, "archive", v)
+	}
+	var a *resource.Archive
+	var err error
+	if isURI(v.StringValue()) {
+		a, err = resource.NewURIArchive(v.StringValue())
+	} else {
+		a, err = resource.NewPathArchive(v.StringValue())
+	}
+	if err != nil {
+		return nil, &pulumirpc.CheckFailure{Property: path, Reason: fmt.Sprintf("invalid archive: %v", err)}
+	}
+	return a, nil
+}
+
+// isOpaqueSchema returns true if values of the given type are not encoded according to their structure, so their
+// contents are never diffed or walked individually.
+func isOpaqueSchema(t reflect.Type) bool {
+	return isUnmarshaler(t) || t == durationType || t == rawMessageType || isBytes(t) || t == assetType ||
+		t == archiveType
+}
+
 type fieldDesc struct {
 	name     string
 	optional bool
 	forceNew bool
+	computed bool // the gateway populates a default value if the property is unset
+	secret   bool // the property holds sensitive data and its outputs are marked as secret
+	set      bool // the order of the elements of an array property is insignificant
+	strict   bool // the property's unset, empty, and default values are distinct when diffing
+
+	immutableWhenSet bool // the property cannot be changed once set, not even by replacing the resource
+
+	enum    []string       // the permitted values of a string property, if restricted
+	min     *float64       // the minimum value of a numeric property, if any
+	max     *float64       // the maximum value of a numeric property, if any
+	pattern *regexp.Regexp // the pattern that a string property must match, if any
+
+	defaultValue *string // the value of an unset optional property, if any, in configuration variable syntax
+	deprecated   string  // the reason that the property is deprecated, if it is
+
+	aliases []string // the former names of the property, which are accepted in place of its name
+
+	conflictsWith []string // the properties that cannot be set along with the property
+	requiredWith  []string // the properties that must be set along with the property
 }
 
 func computeName(fieldName string) string {
@@ -60,22 +264,520 @@ func getFieldDesc(field reflect.StructField) (*fieldDesc, error) {
 			desc.optional = true
 		case "forceNew":
 			desc.forceNew = true
+		case "computed":
+			desc.computed = true
+		case "secret":
+			desc.secret = true
+		case "set":
+			if field.Type.Kind() != reflect.Slice {
+				return nil, errors.Errorf("set option in tag for non-slice struct field %v", field.Name)
+			}
+			desc.set = true
+		case "strict":
+			desc.strict = true
+		case "immutableWhenSet":
+			desc.immutableWhenSet = true
 		default:
+			// Options with values. Values cannot contain commas.
+			key, value := opt, ""
+			if i := strings.IndexByte(opt, '='); i != -1 {
+				key, value = opt[:i], opt[i+1:]
+			}
+			switch key {
+			case "enum":
+				desc.enum = strings.Split(value, "|")
+				continue
+			case "min", "max":
+				bound, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, errors.Errorf("invalid %v '%v' in tag for struct field %v", key, value, field.Name)
+				}
+				if key == "min" {
+					desc.min = &bound
+				} else {
+					desc.max = &bound
+				}
+				continue
+			case "pattern":
+				pattern, err := regexp.Compile(value)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid pattern in tag for struct field %v", field.Name)
+				}
+				desc.pattern = pattern
+				continue
+			case "default":
+				desc.defaultValue = &value
+				continue
+			case "alias":
+				desc.aliases = strings.Split(value, "|")
+				continue
+			case "conflictsWith":
+				desc.conflictsWith = strings.Split(value, "|")
+				continue
+			case "requiredWith":
+				desc.requiredWith = strings.Split(value, "|")
+				continue
+			case "deprecated":
+				if value == "" {
+					return nil, errors.Errorf("missing message for deprecated struct field %v", field.Name)
+				}
+				desc.deprecated = value
+				continue
+			}
 			return nil, errors.Errorf("unknown option '%v' in tag for struct field %v", opt, field.Name)
 		}
 	}
+	if desc.defaultValue != nil && !desc.optional {
+		return nil, errors.Errorf("default value in tag for required struct field %v", field.Name)
+	}
 	return desc, nil
 }
 
+// lookupProperty returns the value of the described property in the given map. The property may be set under its name
+// or, failing that, one of its aliases.
+func lookupProperty(m resource.PropertyMap, desc *fieldDesc) (resource.PropertyValue, bool) {
+	if v, ok := m[resource.PropertyKey(desc.name)]; ok {
+		return v, true
+	}
+	for _, alias := range desc.aliases {
+		if v, ok := m[resource.PropertyKey(alias)]; ok {
+			return v, true
+		}
+	}
+	return resource.PropertyValue{}, false
+}
+
+// structField describes a property of a struct schema. The fields of embedded structs are promoted to the embedding
+// struct, so the index of a field may have more than one element.
+type structField struct {
+	index []int
+	typ   reflect.Type
+	desc  *fieldDesc
+}
+
+// isEmbeddedStruct returns true if the given field is an anonymous struct field whose properties are promoted to the
+// enclosing struct, i.e. it has no pulumi tag of its own.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	if !field.Anonymous || field.Tag.Get("pulumi") != "" {
+		return false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		// Pointers to unexported struct types cannot be allocated when decoding.
+		if field.PkgPath != "" {
+			return false
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// fieldCache holds the result of typeFields for each struct schema, so that struct tags are parsed once per type
+// rather than once per operation. The cached fields must not be modified.
+var fieldCache sync.Map // map[reflect.Type]cachedFields
+
+type cachedFields struct {
+	fields []structField
+	err    error
+}
+
+// structFields returns the properties of the given struct schema. As with encoding/json, the fields of embedded
+// structs are flattened into the enclosing struct, and a field shadows any promoted fields of the same name that are
+// more deeply nested. Promoted fields of the same name at the same depth are an error.
+func structFields(t reflect.Type) ([]structField, error) {
+	if cached, ok := fieldCache.Load(t); ok {
+		c := cached.(cachedFields)
+		return c.fields, c.err
+	}
+	fields, err := typeFields(t)
+	fieldCache.Store(t, cachedFields{fields: fields, err: err})
+	return fields, err
+}
+
+// typeFields computes the properties of the given struct schema for structFields.
+func typeFields(t reflect.Type) ([]structField, error) {
+	var all []structField
+	var collect func(t reflect.Type, index []int, visited map[reflect.Type]bool) error
+	collect = func(t reflect.Type, index []int, visited map[reflect.Type]bool) error {
+		if visited[t] {
+			return nil
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+			if isEmbeddedStruct(f) {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if err := collect(ft, fieldIndex, visited); err != nil {
+					return err
+				}
+				continue
+			}
+
+			desc, err := getFieldDesc(f)
+			if err != nil {
+				return err
+			}
+			if desc != nil {
+				all = append(all, structField{index: fieldIndex, typ: f.Type, desc: desc})
+			}
+		}
+		return nil
+	}
+	if err := collect(t, nil, map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+
+	depths := map[string]int{}
+	for _, f := range all {
+		if d, ok := depths[f.desc.name]; !ok || len(f.index) < d {
+			depths[f.desc.name] = len(f.index)
+		}
+	}
+	var fields []structField
+	seen := map[string]bool{}
+	for _, f := range all {
+		if len(f.index) != depths[f.desc.name] {
+			continue
+		}
+		if seen[f.desc.name] {
+			return nil, errors.Errorf("ambiguous property %v in embedded structs of %v", f.desc.name, t)
+		}
+		seen[f.desc.name] = true
+		fields = append(fields, f)
+	}
+	for _, f := range fields {
+		for _, name := range append(append([]string(nil), f.desc.conflictsWith...), f.desc.requiredWith...) {
+			if !seen[name] || name == f.desc.name {
+				return nil, errors.Errorf("invalid related property %v in tag for property %v of %v",
+					name, f.desc.name, t)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// fieldByIndex returns the field of the given struct value with the given index. Nil pointers to embedded structs
+// along the way are allocated if alloc is true; otherwise the field is reported as unreachable.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// applyDefaults sets each unset optional property of the given object to the default value declared by its field's
+// tag, if any. The properties of nested objects, including those within arrays and maps, are defaulted likewise.
+// Default values are parsed in the same way as configuration variables.
+func applyDefaults(m resource.PropertyMap, schema interface{}) error {
+	return applyDefaultValues(resource.NewObjectProperty(m), reflect.TypeOf(schema))
+}
+
+func applyDefaultValues(v resource.PropertyValue, schema reflect.Type) error {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	v = plainValue(v)
+	if isOpaqueSchema(schema) {
+		return nil
+	}
+
+	switch {
+	case schema.Kind() == reflect.Slice && v.IsArray():
+		for _, e := range v.ArrayValue() {
+			if err := applyDefaultValues(e, schema.Elem()); err != nil {
+				return err
+			}
+		}
+	case schema.Kind() == reflect.Map && v.IsObject():
+		for _, e := range v.ObjectValue() {
+			if err := applyDefaultValues(e, schema.Elem()); err != nil {
+				return err
+			}
+		}
+	case schema.Kind() == reflect.Struct && v.IsObject():
+		fields, err := structFields(schema)
+		if err != nil {
+			return err
+		}
+		m := v.ObjectValue()
+		for _, f := range fields {
+			e, ok := lookupProperty(m, f.desc)
+			switch {
+			case (!ok || e.IsNull()) && f.desc.defaultValue != nil:
+				m[resource.PropertyKey(f.desc.name)] = configValue(*f.desc.defaultValue, f.typ)
+			case ok:
+				if err := applyDefaultValues(e, f.typ); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// foldKey returns the given property key in a form that ignores case and underscore or hyphen separators, so that
+// e.g. "envVars", "EnvVars", "env_vars", and "env-vars" are all equivalent.
+func foldKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// foldPropertyKeys renames the properties of the given object that match a property of the given schema when case
+// and separators are ignored to that property's name. Nested objects are renamed likewise. This is an opt-in
+// leniency for inputs that follow other naming conventions, such as those of stack.yml files, and is enabled by the
+// lenientPropertyKeys configuration key.
+func foldPropertyKeys(m resource.PropertyMap, schema interface{}) error {
+	return foldKeys("", resource.NewObjectProperty(m), reflect.TypeOf(schema))
+}
+
+func foldKeys(path string, v resource.PropertyValue, schema reflect.Type) error {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	v = plainValue(v)
+	if isOpaqueSchema(schema) {
+		return nil
+	}
+
+	switch {
+	case schema.Kind() == reflect.Slice && v.IsArray():
+		for i, e := range v.ArrayValue() {
+			if err := foldKeys(fmt.Sprintf("%v[%v]", path, i), e, schema.Elem()); err != nil {
+				return err
+			}
+		}
+	case schema.Kind() == reflect.Map && v.IsObject():
+		for k, e := range v.ObjectValue() {
+			if err := foldKeys(propertyPath(path, string(k)), e, schema.Elem()); err != nil {
+				return err
+			}
+		}
+	case schema.Kind() == reflect.Struct && v.IsObject():
+		fields, err := structFields(schema)
+		if err != nil {
+			return err
+		}
+		names := map[string]string{}
+		for _, f := range fields {
+			names[foldKey(f.desc.name)] = f.desc.name
+		}
+
+		m := v.ObjectValue()
+		for k, e := range m {
+			name, ok := names[foldKey(string(k))]
+			if !ok || string(k) == name {
+				continue
+			}
+			if _, set := m[resource.PropertyKey(name)]; set {
+				return errors.Errorf("%v: %v and %v refer to the same property", propertyPath(path, string(k)), k, name)
+			}
+			delete(m, k)
+			m[resource.PropertyKey(name)] = e
+		}
+		for _, f := range fields {
+			if e, ok := m[resource.PropertyKey(f.desc.name)]; ok {
+				if err := foldKeys(propertyPath(path, f.desc.name), e, f.typ); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// coerceStrings converts, in place, the string values of the given object's bool and number properties that parse as
+// such to booleans and numbers. Nested objects are converted likewise. It returns a warning for each converted value.
+// This is an opt-in leniency for programs that provide every value as a string, such as YAML programs and converted
+// stack files, and is enabled by the coerceStrings configuration key.
+func coerceStrings(m resource.PropertyMap, schema interface{}) ([]string, error) {
+	var warnings []string
+	_, err := coerceValue("", resource.NewObjectProperty(m), reflect.TypeOf(schema), &warnings)
+	return warnings, err
+}
+
+func coerceValue(path string, v resource.PropertyValue, schema reflect.Type,
+	warnings *[]string) (resource.PropertyValue, error) {
+
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if isOpaqueSchema(schema) {
+		return v, nil
+	}
+	if v.IsSecret() {
+		e, err := coerceValue(path, v.SecretValue().Element, schema, warnings)
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
+		return resource.MakeSecret(e), nil
+	}
+
+	// The converted values are not included in warnings, as they may be secret.
+	switch {
+	case schema.Kind() == reflect.Bool && v.IsString():
+		if b, err := strconv.ParseBool(v.StringValue()); err == nil {
+			*warnings = append(*warnings, fmt.Sprintf("property %v: converted a string to a bool", path))
+			return resource.NewBoolProperty(b), nil
+		}
+	case isNumberKind(schema.Kind()) && v.IsString():
+		if n, err := strconv.ParseFloat(v.StringValue(), 64); err == nil {
+			*warnings = append(*warnings, fmt.Sprintf("property %v: converted a string to a number", path))
+			return resource.NewNumberProperty(n), nil
+		}
+	case schema.Kind() == reflect.Slice && v.IsArray():
+		arr := v.ArrayValue()
+		for i, e := range arr {
+			c, err := coerceValue(fmt.Sprintf("%v[%v]", path, i), e, schema.Elem(), warnings)
+			if err != nil {
+				return resource.PropertyValue{}, err
+			}
+			arr[i] = c
+		}
+	case schema.Kind() == reflect.Map && v.IsObject():
+		m := v.ObjectValue()
+		for k, e := range m {
+			c, err := coerceValue(propertyPath(path, string(k)), e, schema.Elem(), warnings)
+			if err != nil {
+				return resource.PropertyValue{}, err
+			}
+			m[k] = c
+		}
+	case schema.Kind() == reflect.Struct && v.IsObject():
+		fields, err := structFields(schema)
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
+		m := v.ObjectValue()
+		for _, f := range fields {
+			for _, key := range append([]string{f.desc.name}, f.desc.aliases...) {
+				if e, ok := m[resource.PropertyKey(key)]; ok {
+					c, err := coerceValue(propertyPath(path, key), e, f.typ, warnings)
+					if err != nil {
+						return resource.PropertyValue{}, err
+					}
+					m[resource.PropertyKey(key)] = c
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// isNumberKind returns true if the given kind is an integer or floating-point kind.
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// forceNewProperties returns the names of the top-level properties of the given schema that can only be changed by
+// replacing the resource.
+func forceNewProperties(schema interface{}) ([]string, error) {
+	fields, err := structFields(reflect.TypeOf(schema))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range fields {
+		if f.desc.forceNew {
+			names = append(names, f.desc.name)
+		}
+	}
+	return names, nil
+}
+
+// computedProperties returns the names of the top-level properties of the given schema that the gateway populates if
+// they are unset.
+func computedProperties(schema interface{}) ([]string, error) {
+	fields, err := structFields(reflect.TypeOf(schema))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range fields {
+		if f.desc.computed {
+			names = append(names, f.desc.name)
+		}
+	}
+	return names, nil
+}
+
 type checker struct {
 	failures []*pulumirpc.CheckFailure
+
+	// If recordMissing is set, the paths of missing required properties are recorded in missing rather than reported
+	// as failures.
+	recordMissing bool
+	missing       []string
+
+	// warnings holds a message for each deprecated property that is set.
+	warnings []string
 }
 
 func (c *checker) checkProperty(path string, v resource.PropertyValue, schema reflect.Type) error {
+	// Secrets are checked by their values.
+	v = plainValue(v)
 	if v.IsComputed() {
 		return nil
 	}
 
+	if isUnmarshaler(schema) {
+		if _, failure := unmarshalProperty(path, v, schema); failure != nil {
+			c.failures = append(c.failures, failure)
+		}
+		return nil
+	}
+	if schema == durationType {
+		if _, failure := parseDuration(path, v); failure != nil {
+			c.failures = append(c.failures, failure)
+		}
+		return nil
+	}
+	if isBytes(schema) {
+		if _, failure := decodeBytes(path, v); failure != nil {
+			c.failures = append(c.failures, failure)
+		}
+		return nil
+	}
+	if schema == assetType {
+		if _, failure := decodeAsset(path, v); failure != nil {
+			c.failures = append(c.failures, failure)
+		}
+		return nil
+	}
+	if schema == archiveType {
+		if _, failure := decodeArchive(path, v); failure != nil {
+			c.failures = append(c.failures, failure)
+		}
+		return nil
+	}
+	if schema == rawMessageType {
+		if !v.ContainsUnknowns() {
+			if _, failure := marshalRawMessage(path, v); failure != nil {
+				c.failures = append(c.failures, failure)
+			}
+		}
+		return nil
+	}
+
 	switch schema.Kind() {
 	case reflect.Bool:
 		if !v.IsBool() {
@@ -87,6 +789,8 @@ func (c *checker) checkProperty(path string, v resource.PropertyValue, schema re
 		reflect.Float32, reflect.Float64:
 		if !v.IsNumber() {
 			c.failures = append(c.failures, typeMismatch(path, "number", v))
+		} else if failure := numberOutOfRange(path, v.NumberValue(), schema); failure != nil {
+			c.failures = append(c.failures, failure)
 		}
 
 	case reflect.String:
@@ -97,10 +801,11 @@ func (c *checker) checkProperty(path string, v resource.PropertyValue, schema re
 	case reflect.Slice:
 		if !v.IsArray() {
 			c.failures = append(c.failures, typeMismatch(path, "[]", v))
-		}
-		for i, e := range v.ArrayValue() {
-			if err := c.checkProperty(fmt.Sprintf("%v[%v]", path, i), e, schema.Elem()); err != nil {
-				return err
+		} else {
+			for i, e := range v.ArrayValue() {
+				if err := c.checkProperty(fmt.Sprintf("%v[%v]", path, i), e, schema.Elem()); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -112,7 +817,7 @@ func (c *checker) checkProperty(path string, v resource.PropertyValue, schema re
 			c.failures = append(c.failures, typeMismatch(path, "object", v))
 		} else {
 			for k, e := range v.ObjectValue() {
-				if err := c.checkProperty(fmt.Sprintf("%v.%v", path, k), e, schema.Elem()); err != nil {
+				if err := c.checkProperty(propertyPath(path, string(k)), e, schema.Elem()); err != nil {
 					return err
 				}
 			}
@@ -122,27 +827,74 @@ func (c *checker) checkProperty(path string, v resource.PropertyValue, schema re
 		if !v.IsObject() {
 			c.failures = append(c.failures, typeMismatch(path, "object", v))
 		} else {
+			fields, err := structFields(schema)
+			if err != nil {
+				return err
+			}
 			m := v.ObjectValue()
-			for i := 0; i < schema.NumField(); i++ {
-				f := schema.Field(i)
-				desc, err := getFieldDesc(f)
-				if err != nil {
-					return err
+			isSet := func(name string) (set, known bool) {
+				for _, f := range fields {
+					if f.desc.name == name {
+						e, ok := lookupProperty(m, f.desc)
+						e = plainValue(e)
+						return ok && !e.IsNull(), !e.IsComputed()
+					}
 				}
-				if desc == nil {
-					continue
+				return false, true
+			}
+			for _, f := range fields {
+				desc := f.desc
+				for _, alias := range desc.aliases {
+					if _, ok := m[resource.PropertyKey(alias)]; ok {
+						if _, ok = m[resource.PropertyKey(desc.name)]; ok {
+							c.failures = append(c.failures, &pulumirpc.CheckFailure{
+								Property: propertyPath(path, alias),
+								Reason:   fmt.Sprintf("%v is an alias of %v; only one may be set", alias, desc.name),
+							})
+						}
+					}
 				}
 
-				e, ok := m[resource.PropertyKey(desc.name)]
-				if !ok || e.IsNull() {
-					if !desc.optional {
+				e, ok := lookupProperty(m, desc)
+				if !ok || plainValue(e).IsNull() {
+					switch {
+					case desc.optional:
+					case c.recordMissing:
+						c.missing = append(c.missing, propertyPath(path, desc.name))
+					default:
 						c.failures = append(c.failures, missingRequiredProperty(path, desc.name))
 					}
 					continue
 				}
-				if err := c.checkProperty(fmt.Sprintf("%v.%v", path, desc.name), e, f.Type); err != nil {
+				if desc.deprecated != "" {
+					c.warnings = append(c.warnings,
+						fmt.Sprintf("property %v is deprecated: %v", propertyPath(path, desc.name), desc.deprecated))
+				}
+				if err := c.checkProperty(propertyPath(path, desc.name), e, f.typ); err != nil {
 					return err
 				}
+				c.checkConstraints(propertyPath(path, desc.name), e, desc)
+
+				// Relationships are only enforced once the property and its relatives are known.
+				if plainValue(e).IsComputed() {
+					continue
+				}
+				for _, other := range desc.conflictsWith {
+					if set, known := isSet(other); set && known {
+						c.failures = append(c.failures, &pulumirpc.CheckFailure{
+							Property: propertyPath(path, desc.name),
+							Reason:   fmt.Sprintf("%v and %v cannot both be specified", other, desc.name),
+						})
+					}
+				}
+				for _, other := range desc.requiredWith {
+					if set, _ := isSet(other); !set {
+						c.failures = append(c.failures, &pulumirpc.CheckFailure{
+							Property: propertyPath(path, desc.name),
+							Reason:   fmt.Sprintf("%v must also be specified when %v is specified", other, desc.name),
+						})
+					}
+				}
 			}
 		}
 
@@ -151,61 +903,249 @@ func (c *checker) checkProperty(path string, v resource.PropertyValue, schema re
 			return c.checkProperty(path, v, schema.Elem())
 		}
 
+	case reflect.Interface:
+		// Arbitrary values are passed through without enforcing a schema.
+		if schema.NumMethod() != 0 {
+			return errors.Errorf("unsupported type %v", schema)
+		}
+
 	default:
 		return errors.Errorf("unsupported type %v", schema.Name())
 	}
 
-	return nil
+	return nil
+}
+
+// checkConstraints checks the given property value against the constraints declared by its field's tag. Values of the
+// wrong type have already been reported by checkProperty and are ignored here. The elements of arrays are checked
+// individually.
+func (c *checker) checkConstraints(path string, v resource.PropertyValue, desc *fieldDesc) {
+	fail := func(format string, args ...interface{}) {
+		c.failures = append(c.failures, &pulumirpc.CheckFailure{Property: path, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	switch v = plainValue(v); {
+	case v.IsArray():
+		for i, e := range v.ArrayValue() {
+			c.checkConstraints(fmt.Sprintf("%v[%v]", path, i), e, desc)
+		}
+
+	case v.IsString():
+		s := v.StringValue()
+		if desc.enum != nil && !containsString(desc.enum, s) {
+			fail("expected one of %v, received %q", strings.Join(desc.enum, ", "), s)
+		}
+		if desc.pattern != nil && !desc.pattern.MatchString(s) {
+			fail("expected a value matching %v, received %q", desc.pattern, s)
+		}
+
+	case v.IsNumber():
+		n := v.NumberValue()
+		if desc.min != nil && n < *desc.min {
+			fail("expected a value of at least %v, received %v", *desc.min, n)
+		}
+		if desc.max != nil && n > *desc.max {
+			fail("expected a value of at most %v, received %v", *desc.max, n)
+		}
+	}
+}
+
+// containsString returns true if the given slice contains the given string.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func checkProperties(m resource.PropertyMap, schema interface{}) ([]*pulumirpc.CheckFailure, error) {
+	failures, _, err := checkPropertiesWithWarnings(m, schema)
+	return failures, err
+}
+
+// checkPropertiesWithWarnings checks the given properties against the given schema like checkProperties. It also
+// returns a warning for each deprecated property that is set; these do not cause the check to fail.
+func checkPropertiesWithWarnings(m resource.PropertyMap,
+	schema interface{}) ([]*pulumirpc.CheckFailure, []string, error) {
+
+	c := &checker{}
+	if err := c.checkProperty("", resource.NewObjectProperty(m), reflect.TypeOf(schema)); err != nil {
+		return nil, nil, err
+	}
+	return c.failures, c.warnings, nil
+}
+
+// decoder decodes property values into Go values according to the types of the destination values.
+type decoder struct {
+	// If recordUnknowns is set, unknown values are decoded as zero values and their paths are recorded in unknowns
+	// rather than reported as type mismatches.
+	recordUnknowns bool
+	unknowns       []string
+
+	// failures holds the problems with the decoded values. Decoding continues past failures so that they can all be
+	// reported at once.
+	failures []*pulumirpc.CheckFailure
+
+	// secrets holds the paths of the values that were secret. Secrets are decoded by their values, so values that are
+	// derived from them must be marked as secret again when they are encoded.
+	secrets []string
+}
+
+// fail records a problem with a decoded value.
+func (d *decoder) fail(f *pulumirpc.CheckFailure) {
+	d.failures = append(d.failures, f)
+}
+
+// err returns an error that reports all of the decoder's failures, if any.
+func (d *decoder) err() error {
+	if len(d.failures) == 0 {
+		return nil
+	}
+	return &decodeError{failures: d.failures}
+}
+
+// decodeError reports the problems with a set of decoded properties.
+type decodeError struct {
+	failures []*pulumirpc.CheckFailure
 }
 
-func checkProperties(m resource.PropertyMap, schema interface{}) ([]*pulumirpc.CheckFailure, error) {
-	c := &checker{}
-	if err := c.checkProperty("", resource.NewObjectProperty(m), reflect.TypeOf(schema)); err != nil {
-		return nil, err
+func (e *decodeError) Error() string {
+	msgs := make([]string, len(e.failures))
+	for i, f := range e.failures {
+		msgs[i] = failureError(f).Error()
 	}
-	return c.failures, nil
+	return strings.Join(msgs, "; ")
 }
 
-func decodeProperty(path string, v resource.PropertyValue, dest reflect.Value) error {
+func (d *decoder) decodeProperty(path string, v resource.PropertyValue, dest reflect.Value) error {
+	if v.IsSecret() {
+		d.secrets = append(d.secrets, path)
+		v = plainValue(v)
+	}
+	if d.recordUnknowns && (v.IsComputed() || v.IsOutput()) {
+		d.unknowns = append(d.unknowns, path)
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+
+	if isUnmarshaler(dest.Type()) {
+		value, failure := unmarshalProperty(path, v, dest.Type())
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.Set(value)
+		return nil
+	}
+	if dest.Type() == durationType {
+		duration, failure := parseDuration(path, v)
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.SetInt(int64(duration))
+		return nil
+	}
+	if isBytes(dest.Type()) {
+		b, failure := decodeBytes(path, v)
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.SetBytes(b)
+		return nil
+	}
+	if dest.Type() == assetType {
+		a, failure := decodeAsset(path, v)
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.Set(reflect.ValueOf(*a))
+		return nil
+	}
+	if dest.Type() == archiveType {
+		a, failure := decodeArchive(path, v)
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.Set(reflect.ValueOf(*a))
+		return nil
+	}
+	if dest.Type() == rawMessageType {
+		if v.IsNull() {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		raw, failure := marshalRawMessage(path, v)
+		if failure != nil {
+			d.fail(failure)
+			return nil
+		}
+		dest.Set(reflect.ValueOf(raw))
+		return nil
+	}
+
 	switch dest.Kind() {
 	case reflect.Bool:
 		if !v.IsBool() {
-			return failureError(typeMismatch(path, "bool", v))
+			d.fail(typeMismatch(path, "bool", v))
+			return nil
 		}
 		dest.SetBool(v.BoolValue())
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if !v.IsNumber() {
-			return failureError(typeMismatch(path, "number", v))
+			d.fail(typeMismatch(path, "number", v))
+			return nil
+		}
+		if failure := numberOutOfRange(path, v.NumberValue(), dest.Type()); failure != nil {
+			d.fail(failure)
+			return nil
 		}
 		dest.SetInt(int64(v.NumberValue()))
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if !v.IsNumber() {
-			return failureError(typeMismatch(path, "number", v))
+			d.fail(typeMismatch(path, "number", v))
+			return nil
+		}
+		if failure := numberOutOfRange(path, v.NumberValue(), dest.Type()); failure != nil {
+			d.fail(failure)
+			return nil
 		}
 		dest.SetUint(uint64(v.NumberValue()))
 
 	case reflect.Float32, reflect.Float64:
 		if !v.IsNumber() {
-			return failureError(typeMismatch(path, "number", v))
+			d.fail(typeMismatch(path, "number", v))
+			return nil
+		}
+		if failure := numberOutOfRange(path, v.NumberValue(), dest.Type()); failure != nil {
+			d.fail(failure)
+			return nil
 		}
 		dest.SetFloat(v.NumberValue())
 
 	case reflect.String:
 		if !v.IsString() {
-			return failureError(typeMismatch(path, "string", v))
+			d.fail(typeMismatch(path, "string", v))
+			return nil
 		}
 		dest.SetString(v.StringValue())
 
 	case reflect.Slice:
 		if !v.IsArray() {
-			return failureError(typeMismatch(path, "[]", v))
+			d.fail(typeMismatch(path, "[]", v))
+			return nil
 		}
 		arrayValue := v.ArrayValue()
 		slice := reflect.MakeSlice(dest.Type(), len(arrayValue), len(arrayValue))
 		for i, e := range arrayValue {
-			if err := decodeProperty(fmt.Sprintf("%v[%v]", path, i), e, slice.Index(i)); err != nil {
+			if err := d.decodeProperty(fmt.Sprintf("%v[%v]", path, i), e, slice.Index(i)); err != nil {
 				return err
 			}
 		}
@@ -216,12 +1156,13 @@ func decodeProperty(path string, v resource.PropertyValue, dest reflect.Value) e
 			return errors.New("map schema must have string keys")
 		}
 		if !v.IsObject() {
-			return failureError(typeMismatch(path, "object", v))
+			d.fail(typeMismatch(path, "object", v))
+			return nil
 		}
 		m := reflect.MakeMap(dest.Type())
 		for k, e := range v.ObjectValue() {
 			me := reflect.New(dest.Type().Elem()).Elem()
-			if err := decodeProperty(fmt.Sprintf("%v.%v", path, k), e, me); err != nil {
+			if err := d.decodeProperty(propertyPath(path, string(k)), e, me); err != nil {
 				return err
 			}
 			m.SetMapIndex(reflect.ValueOf(string(k)), me)
@@ -230,28 +1171,30 @@ func decodeProperty(path string, v resource.PropertyValue, dest reflect.Value) e
 
 	case reflect.Struct:
 		if !v.IsObject() {
-			return failureError(typeMismatch(path, "object", v))
+			d.fail(typeMismatch(path, "object", v))
+			return nil
+		}
+		fields, err := structFields(dest.Type())
+		if err != nil {
+			return err
 		}
 		m := v.ObjectValue()
-		for i := 0; i < dest.NumField(); i++ {
-			f := dest.Field(i)
-			desc, err := getFieldDesc(dest.Type().Field(i))
-			if err != nil {
-				return err
-			}
-			if desc == nil {
-				continue
-			}
-
-			e, ok := m[resource.PropertyKey(desc.name)]
+		for _, sf := range fields {
+			desc := sf.desc
+			e, ok := lookupProperty(m, desc)
 			if !ok || e.IsNull() {
 				if !desc.optional {
-					return failureError(missingRequiredProperty(path, desc.name))
+					d.fail(missingRequiredProperty(path, desc.name))
+					continue
+				}
+				// Leave nil embedded structs unallocated rather than allocating them only to clear their fields.
+				if f, ok := fieldByIndex(dest, sf.index, false); ok {
+					f.Set(reflect.Zero(f.Type()))
 				}
-				f.Set(reflect.Zero(f.Type()))
 				continue
 			}
-			if err := decodeProperty(fmt.Sprintf("%v.%v", path, desc.name), e, f); err != nil {
+			f, _ := fieldByIndex(dest, sf.index, true)
+			if err := d.decodeProperty(propertyPath(path, desc.name), e, f); err != nil {
 				return err
 			}
 		}
@@ -263,11 +1206,21 @@ func decodeProperty(path string, v resource.PropertyValue, dest reflect.Value) e
 			if dest.IsNil() {
 				dest.Set(reflect.New(dest.Type().Elem()))
 			}
-			if err := decodeProperty(path, v, dest.Elem()); err != nil {
+			if err := d.decodeProperty(path, v, dest.Elem()); err != nil {
 				return err
 			}
 		}
 
+	case reflect.Interface:
+		if dest.NumMethod() != 0 {
+			return errors.Errorf("unsupported type %v", dest.Type())
+		}
+		if v.IsNull() {
+			dest.Set(reflect.Zero(dest.Type()))
+		} else {
+			dest.Set(reflect.ValueOf(v.Mappable()))
+		}
+
 	default:
 		return errors.Errorf("unsupported type %v", dest.Type().Name())
 	}
@@ -280,10 +1233,79 @@ func decodeProperties(m resource.PropertyMap, dest interface{}) error {
 	if v.Kind() != reflect.Ptr {
 		return errors.New("dest type must be a pointer")
 	}
-	return decodeProperty("", resource.NewObjectProperty(m), v)
+	d := &decoder{}
+	if err := d.decodeProperty("", resource.NewObjectProperty(m), v); err != nil {
+		return err
+	}
+	return d.err()
+}
+
+// decodePartialProperties decodes the given properties, some of which may be unknown, into the given destination.
+// Unknown values are left zero, and their paths are returned.
+func decodePartialProperties(m resource.PropertyMap, dest interface{}) ([]string, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return nil, errors.New("dest type must be a pointer")
+	}
+	d := &decoder{recordUnknowns: true}
+	if err := d.decodeProperty("", resource.NewObjectProperty(m), v); err != nil {
+		return nil, err
+	}
+	return d.unknowns, d.err()
+}
+
+// decodeSecretProperties decodes the given properties into the given destination like decodeProperties. It also
+// returns the paths of the values that were secret, for use with markSecretPaths.
+func decodeSecretProperties(m resource.PropertyMap, dest interface{}) ([]string, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return nil, errors.New("dest type must be a pointer")
+	}
+	d := &decoder{}
+	if err := d.decodeProperty("", resource.NewObjectProperty(m), v); err != nil {
+		return nil, err
+	}
+	return d.secrets, d.err()
 }
 
 func encodeProperty(v reflect.Value) (resource.PropertyValue, error) {
+	if v.Type().Implements(propertyMarshalerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		return v.Interface().(PropertyMarshaler).MarshalProperty()
+	}
+	if v.Type() == durationType {
+		return resource.NewStringProperty(time.Duration(v.Int()).String()), nil
+	}
+	if isBytes(v.Type()) {
+		if v.IsNil() {
+			return resource.NewNullProperty(), nil
+		}
+		return resource.NewStringProperty(base64.StdEncoding.EncodeToString(v.Bytes())), nil
+	}
+	if v.Type() == assetType {
+		a := v.Interface().(resource.Asset)
+		if a == (resource.Asset{}) {
+			return resource.NewNullProperty(), nil
+		}
+		return resource.NewAssetProperty(&a), nil
+	}
+	if v.Type() == archiveType {
+		a := v.Interface().(resource.Archive)
+		if reflect.DeepEqual(a, resource.Archive{}) {
+			return resource.NewNullProperty(), nil
+		}
+		return resource.NewArchiveProperty(&a), nil
+	}
+	if v.Type() == rawMessageType {
+		if v.Len() == 0 {
+			return resource.NewNullProperty(), nil
+		}
+		var value interface{}
+		if err := json.Unmarshal(v.Bytes(), &value); err != nil {
+			return resource.PropertyValue{}, errors.Wrap(err, "invalid JSON")
+		}
+		return resource.NewPropertyValue(value), nil
+	}
+
 	switch v.Kind() {
 	case reflect.Bool:
 		return resource.NewBoolProperty(v.Bool()), nil
@@ -326,22 +1348,23 @@ func encodeProperty(v reflect.Value) (resource.PropertyValue, error) {
 		return resource.NewObjectProperty(m), nil
 
 	case reflect.Struct:
+		fields, err := structFields(v.Type())
+		if err != nil {
+			return resource.PropertyValue{}, err
+		}
 		m := make(resource.PropertyMap)
-		for i := 0; i < v.NumField(); i++ {
-			f := v.Field(i)
-			desc, err := getFieldDesc(v.Type().Field(i))
-			if err != nil {
-				return resource.PropertyValue{}, err
-			}
-			if desc == nil {
-				continue
+		for _, sf := range fields {
+			// The fields of nil embedded structs are encoded as if they were nil themselves.
+			e := resource.NewNullProperty()
+			if f, ok := fieldByIndex(v, sf.index, false); ok {
+				if e, err = encodeProperty(f); err != nil {
+					return resource.PropertyValue{}, err
+				}
 			}
-
-			e, err := encodeProperty(f)
-			if err != nil {
-				return resource.PropertyValue{}, err
+			if sf.desc.secret && !e.IsNull() {
+				e = resource.MakeSecret(e)
 			}
-			m[resource.PropertyKey(desc.name)] = e
+			m[resource.PropertyKey(sf.desc.name)] = e
 		}
 		return resource.NewObjectProperty(m), nil
 
@@ -351,6 +1374,15 @@ func encodeProperty(v reflect.Value) (resource.PropertyValue, error) {
 		}
 		return encodeProperty(v.Elem())
 
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return resource.PropertyValue{}, errors.Errorf("unsupported type %v", v.Type())
+		}
+		if v.IsNil() {
+			return resource.NewNullProperty(), nil
+		}
+		return resource.NewPropertyValue(v.Interface()), nil
+
 	default:
 		return resource.PropertyValue{}, errors.Errorf("unsupported type %v", v.Type().Name())
 	}
@@ -367,11 +1399,201 @@ func encodeProperties(src interface{}) (resource.PropertyMap, error) {
 	return v.ObjectValue(), nil
 }
 
+// markSecrets marks the values of the given properties that correspond to secret fields of the given schema as
+// secret. Secret fields of nested structs are marked as well.
+func markSecrets(m resource.PropertyMap, schema interface{}) error {
+	return markSecretFields(m, reflect.TypeOf(schema))
+}
+
+// markSecretFields marks the values of the given properties that correspond to secret fields of the given struct type
+// as secret.
+func markSecretFields(m resource.PropertyMap, t reflect.Type) error {
+	fields, err := structFields(t)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		key := resource.PropertyKey(f.desc.name)
+		v, ok := m[key]
+		if !ok || v.IsNull() || v.IsComputed() || v.IsSecret() {
+			continue
+		}
+		if f.desc.secret {
+			m[key] = resource.MakeSecret(v)
+			continue
+		}
+		if m[key], err = markSecretValue(v, f.typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markSecretValue returns the given value of the given schema type with the values of any secret fields of the
+// structs within it marked as secret.
+func markSecretValue(v resource.PropertyValue, schema reflect.Type) (resource.PropertyValue, error) {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if isOpaqueSchema(schema) {
+		return v, nil
+	}
+
+	switch {
+	case schema.Kind() == reflect.Struct && v.IsObject():
+		m := v.ObjectValue().Copy()
+		if err := markSecretFields(m, schema); err != nil {
+			return resource.PropertyValue{}, err
+		}
+		return resource.NewObjectProperty(m), nil
+	case (schema.Kind() == reflect.Slice || schema.Kind() == reflect.Array) && v.IsArray():
+		elems := make([]resource.PropertyValue, len(v.ArrayValue()))
+		for i, e := range v.ArrayValue() {
+			var err error
+			if elems[i], err = markSecretValue(e, schema.Elem()); err != nil {
+				return resource.PropertyValue{}, err
+			}
+		}
+		return resource.NewArrayProperty(elems), nil
+	case schema.Kind() == reflect.Map && v.IsObject():
+		m := make(resource.PropertyMap, len(v.ObjectValue()))
+		for k, e := range v.ObjectValue() {
+			var err error
+			if m[k], err = markSecretValue(e, schema.Elem()); err != nil {
+				return resource.PropertyValue{}, err
+			}
+		}
+		return resource.NewObjectProperty(m), nil
+	}
+	return v, nil
+}
+
+// markSecretPaths marks the properties of the given map that contain the given paths as secret. Only top-level
+// properties are marked, which may mark more than the given values, but never less.
+func markSecretPaths(m resource.PropertyMap, paths []string) {
+	for _, path := range paths {
+		name := path
+		if i := strings.IndexAny(path, ".["); i != -1 {
+			name = path[:i]
+		}
+		key := resource.PropertyKey(name)
+		if v, ok := m[key]; ok && !v.IsNull() && !v.IsComputed() && !v.IsSecret() {
+			m[key] = resource.MakeSecret(v)
+		}
+	}
+}
+
+// plainValue returns the value wrapped by the given value if it is a secret, and the value itself otherwise.
+func plainValue(v resource.PropertyValue) resource.PropertyValue {
+	for v.IsSecret() {
+		v = v.SecretValue().Element
+	}
+	return v
+}
+
+// propertyPath returns the path of the named property of the object at the given path.
+func propertyPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return fmt.Sprintf("%v.%v", path, name)
+}
+
+// isObjectSchema returns true if values of the given schema type are structured objects.
+func isObjectSchema(schema reflect.Type) bool {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	return schema.Kind() == reflect.Struct
+}
+
 type differ struct {
-	replaces []string
+	replaces     []string
+	detailedDiff map[string]*pulumirpc.PropertyDiff
+
+	// If enforceImmutable is set, the paths of the immutableWhenSet properties that changed are recorded in
+	// immutables.
+	enforceImmutable bool
+	immutables       []string
+}
+
+// addDiff records a detailed diff entry of the given kind for the property at the given path.
+func (d *differ) addDiff(path string, kind pulumirpc.PropertyDiff_Kind, replace bool) {
+	if replace {
+		switch kind {
+		case pulumirpc.PropertyDiff_ADD:
+			kind = pulumirpc.PropertyDiff_ADD_REPLACE
+		case pulumirpc.PropertyDiff_DELETE:
+			kind = pulumirpc.PropertyDiff_DELETE_REPLACE
+		case pulumirpc.PropertyDiff_UPDATE:
+			kind = pulumirpc.PropertyDiff_UPDATE_REPLACE
+		}
+	}
+	d.detailedDiff[path] = &pulumirpc.PropertyDiff{Kind: kind}
+}
+
+// replaceNested marks the detailed diff entries for the values within the value at the given path as replacements.
+func (d *differ) replaceNested(path string) {
+	for p, diff := range d.detailedDiff {
+		if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			d.addDiff(p, diff.Kind, true)
+		}
+	}
+}
+
+// isNestedDiff returns true if a change from the given old value to the given new value is recorded by the changes to
+// the values within it rather than as a change to the value as a whole.
+func isNestedDiff(oldV, newV resource.PropertyValue, schema reflect.Type) bool {
+	oldV, newV = plainValue(oldV), plainValue(newV)
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if isOpaqueSchema(schema) {
+		return false
+	}
+
+	switch schema.Kind() {
+	case reflect.Struct, reflect.Map:
+		return oldV.IsObject() && newV.IsObject()
+	case reflect.Slice:
+		return oldV.IsArray() && newV.IsArray()
+	default:
+		return false
+	}
+}
+
+// diffElement diffs the given element of an array or map, recording the change in the detailed diff.
+func (d *differ) diffElement(path string, oldV, newV resource.PropertyValue, schema reflect.Type) (bool, error) {
+	replaces := len(d.replaces)
+	diff, err := d.diffProperty(path, oldV, newV, schema)
+	if err != nil || !diff {
+		return false, err
+	}
+	if !isNestedDiff(oldV, newV, schema) {
+		d.addDiff(path, pulumirpc.PropertyDiff_UPDATE, len(d.replaces) > replaces)
+	}
+	return true, nil
+}
+
+// addElement records the addition of the given element to an array or map in the detailed diff.
+func (d *differ) addElement(path string, v resource.PropertyValue, schema reflect.Type) {
+	replaces := len(d.replaces)
+	d.replaceWithin(path, v, schema)
+	d.addDiff(path, pulumirpc.PropertyDiff_ADD, len(d.replaces) > replaces)
+}
+
+// removeElement records the removal of the given element from an array or map in the detailed diff.
+func (d *differ) removeElement(path string, v resource.PropertyValue, schema reflect.Type) {
+	replaces := len(d.replaces)
+	d.replaceWithin(path, v, schema)
+	d.addDiff(path, pulumirpc.PropertyDiff_DELETE, len(d.replaces) > replaces)
 }
 
 func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, schema reflect.Type) (bool, error) {
+	// Secrets are compared by their values. The detailed diff records only the kind of each change, so their values
+	// are not revealed.
+	oldV, newV = plainValue(oldV), plainValue(newV)
+
 	if oldV.IsComputed() {
 		return false, errors.New("old properties must not be computed")
 	}
@@ -380,6 +1602,68 @@ func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, sc
 		return true, nil
 	}
 
+	if isUnmarshaler(schema) {
+		// Compare the decoded values rather than their representations.
+		oldValue, failure := unmarshalProperty(path, oldV, schema)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		newValue, failure := unmarshalProperty(path, newV, schema)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		return !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()), nil
+	}
+	if schema == durationType {
+		// Compare durations rather than their representations so that e.g. "60s" and "1m" are equal.
+		oldD, failure := parseDuration(path, oldV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		newD, failure := parseDuration(path, newV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		return oldD != newD, nil
+	}
+	if isBytes(schema) {
+		oldB, failure := decodeBytes(path, oldV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		newB, failure := decodeBytes(path, newV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		return !bytes.Equal(oldB, newB), nil
+	}
+	if schema == assetType {
+		// Compare assets by their hashes where available rather than by how they were specified.
+		oldA, failure := decodeAsset(path, oldV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		newA, failure := decodeAsset(path, newV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		return !oldA.Equals(newA), nil
+	}
+	if schema == archiveType {
+		oldA, failure := decodeArchive(path, oldV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		newA, failure := decodeArchive(path, newV)
+		if failure != nil {
+			return false, failureError(failure)
+		}
+		return !oldA.Equals(newA), nil
+	}
+	if schema == rawMessageType {
+		return !oldV.DeepEquals(newV), nil
+	}
+
 	switch schema.Kind() {
 	case reflect.Bool:
 		if !oldV.IsBool() {
@@ -424,12 +1708,20 @@ func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, sc
 		if len(oldArr) != len(newArr) {
 			changed = true
 		}
-		for i := 0; i < len(oldArr) && i < len(newArr); i++ {
-			diff, err := d.diffProperty(fmt.Sprintf("%v[%v]", path, i), oldArr[i], newArr[i], schema.Elem())
-			if err != nil {
-				return false, err
+		for i := 0; i < len(oldArr) || i < len(newArr); i++ {
+			elemPath := fmt.Sprintf("%v[%v]", path, i)
+			switch {
+			case i >= len(newArr):
+				d.removeElement(elemPath, oldArr[i], schema.Elem())
+			case i >= len(oldArr):
+				d.addElement(elemPath, newArr[i], schema.Elem())
+			default:
+				diff, err := d.diffElement(elemPath, oldArr[i], newArr[i], schema.Elem())
+				if err != nil {
+					return false, err
+				}
+				changed = changed || diff
 			}
-			changed = changed || diff
 		}
 		return changed, nil
 
@@ -450,17 +1742,19 @@ func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, sc
 			newE, ok := newObject[k]
 			if !ok {
 				changed = true
+				d.removeElement(propertyPath(path, string(k)), oldE, schema.Elem())
 			} else {
-				diff, err := d.diffProperty(fmt.Sprintf("%v.%v", path, k), oldE, newE, schema.Elem())
+				diff, err := d.diffElement(propertyPath(path, string(k)), oldE, newE, schema.Elem())
 				if err != nil {
 					return false, err
 				}
 				changed = changed || diff
 			}
 		}
-		for k := range newObject {
+		for k, newE := range newObject {
 			if _, ok := oldObject[k]; !ok {
 				changed = true
+				d.addElement(propertyPath(path, string(k)), newE, schema.Elem())
 			}
 		}
 		return changed, nil
@@ -473,39 +1767,62 @@ func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, sc
 			return false, failureError(typeMismatch(path, "object", newV))
 		}
 
+		fields, err := structFields(schema)
+		if err != nil {
+			return false, err
+		}
 		oldObject, newObject := oldV.ObjectValue(), newV.ObjectValue()
 		changed := false
-		for i := 0; i < schema.NumField(); i++ {
-			f := schema.Field(i)
-			desc, err := getFieldDesc(f)
-			if err != nil {
-				return false, err
-			}
-			if desc == nil {
-				continue
-			}
-
-			key, name := resource.PropertyKey(desc.name), fmt.Sprintf("%v.%v", path, desc.name)
+		for _, f := range fields {
+			desc := f.desc
+			name := propertyPath(path, desc.name)
 
-			oldE, hasOld := oldObject[key]
-			newE, hasNew := newObject[key]
+			oldE, hasOld := diffValue(oldObject, f)
+			newE, hasNew := diffValue(newObject, f)
 
-			diff := false
+			diff, kind, replaces := false, pulumirpc.PropertyDiff_UPDATE, len(d.replaces)
 			switch {
 			case !hasOld && !hasNew:
+			case hasOld && hasNew && desc.set:
+				diff, err = diffSet(name, oldE, newE)
+				if err != nil {
+					return false, err
+				}
 			case hasOld && hasNew:
-				diff, err = d.diffProperty(name, oldE, newE, f.Type)
+				diff, err = d.diffProperty(name, oldE, newE, f.typ)
 				if err != nil {
 					return false, err
 				}
-			default:
-				diff = true
+			case hasNew:
+				diff, kind = true, pulumirpc.PropertyDiff_ADD
+			case !desc.computed:
+				// Computed properties that are absent from the new properties retain the value populated by the
+				// gateway, so their removal is not a change.
+				diff, kind = true, pulumirpc.PropertyDiff_DELETE
 			}
 
+			if diff && desc.immutableWhenSet && hasOld && d.enforceImmutable && !plainValue(newE).IsComputed() {
+				d.immutables = append(d.immutables, name)
+			}
 			if diff {
 				changed = true
-				if desc.forceNew {
+				switch {
+				case desc.forceNew:
 					d.replaces = append(d.replaces, name)
+				case kind == pulumirpc.PropertyDiff_ADD:
+					d.replaceWithin(name, newE, f.typ)
+				case kind == pulumirpc.PropertyDiff_DELETE:
+					d.replaceWithin(name, oldE, f.typ)
+				}
+
+				// Changes to nested objects, arrays, and maps are recorded by the values that changed within them.
+				// Values that were set or unset in their entirety are recorded here, as a replacement if they held any
+				// forceNew properties.
+				switch {
+				case kind != pulumirpc.PropertyDiff_UPDATE || desc.set || !isNestedDiff(oldE, newE, f.typ):
+					d.addDiff(name, kind, len(d.replaces) > replaces)
+				case desc.forceNew:
+					d.replaceNested(name)
 				}
 			}
 		}
@@ -517,21 +1834,161 @@ func (d *differ) diffProperty(path string, oldV, newV resource.PropertyValue, sc
 			return false, nil
 		case !oldV.IsNull() && !newV.IsNull():
 			return d.diffProperty(path, oldV, newV, schema.Elem())
+		case oldV.IsNull():
+			d.replaceWithin(path, newV, schema.Elem())
+			return true, nil
 		default:
+			d.replaceWithin(path, oldV, schema.Elem())
 			return true, nil
 		}
 
+	case reflect.Interface:
+		if schema.NumMethod() != 0 {
+			return false, errors.Errorf("unsupported type %v", schema)
+		}
+		return !oldV.DeepEquals(newV), nil
+
 	default:
 		return false, errors.Errorf("unsupported type %v", schema.Name())
 	}
 }
 
-func diffProperties(olds, news resource.PropertyMap, schema interface{}) (bool, []string, error) {
-	d := &differ{}
+// diffValue returns the value of the given property for diffing and true, or false if the property is unset. Null
+// properties are unset. Unless the property is strict, empty arrays and maps are also unset, and unset properties take
+// their default values, so that e.g. a map that the gateway reports as empty does not differ from an unset map.
+func diffValue(m resource.PropertyMap, f structField) (resource.PropertyValue, bool) {
+	v, ok := lookupProperty(m, f.desc)
+	if !ok || v.IsNull() {
+		v, ok = resource.PropertyValue{}, false
+	} else if !f.desc.strict && isEmptyCollection(v, f.typ) {
+		ok = false
+	}
+	if !ok && !f.desc.strict && f.desc.defaultValue != nil {
+		return configValue(*f.desc.defaultValue, f.typ), true
+	}
+	return v, ok
+}
+
+// isEmptyCollection returns true if the given value is an empty array or map of the given slice or map schema.
+func isEmptyCollection(v resource.PropertyValue, schema reflect.Type) bool {
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if isOpaqueSchema(schema) {
+		return false
+	}
+	v = plainValue(v)
+	switch schema.Kind() {
+	case reflect.Slice:
+		return v.IsArray() && len(v.ArrayValue()) == 0
+	case reflect.Map:
+		return v.IsObject() && len(v.ObjectValue()) == 0
+	}
+	return false
+}
+
+// replaceWithin records a replacement for each forceNew property that is set within the given value, which has been
+// added or removed in its entirety.
+func (d *differ) replaceWithin(path string, v resource.PropertyValue, schema reflect.Type) {
+	v = plainValue(v)
+	for schema.Kind() == reflect.Ptr {
+		schema = schema.Elem()
+	}
+	if v.IsNull() || v.IsComputed() || isOpaqueSchema(schema) {
+		return
+	}
+
+	switch {
+	case schema.Kind() == reflect.Slice && v.IsArray():
+		for i, e := range v.ArrayValue() {
+			d.replaceWithin(fmt.Sprintf("%v[%v]", path, i), e, schema.Elem())
+		}
+	case schema.Kind() == reflect.Map && v.IsObject():
+		for k, e := range v.ObjectValue() {
+			d.replaceWithin(propertyPath(path, string(k)), e, schema.Elem())
+		}
+	case schema.Kind() == reflect.Struct && v.IsObject():
+		fields, err := structFields(schema)
+		if err != nil {
+			// The schema is validated when the properties are checked.
+			return
+		}
+		m := v.ObjectValue()
+		for _, f := range fields {
+			e, ok := lookupProperty(m, f.desc)
+			switch {
+			case !ok || e.IsNull():
+			case f.desc.forceNew:
+				d.replaces = append(d.replaces, propertyPath(path, f.desc.name))
+			default:
+				d.replaceWithin(propertyPath(path, f.desc.name), e, f.typ)
+			}
+		}
+	}
+}
+
+// diffSet returns true if the given arrays do not have the same elements, regardless of their order.
+func diffSet(path string, oldV, newV resource.PropertyValue) (bool, error) {
+	oldV, newV = plainValue(oldV), plainValue(newV)
+	if newV.IsComputed() {
+		return true, nil
+	}
+	if !oldV.IsArray() {
+		return false, failureError(typeMismatch(path, "[]", oldV))
+	}
+	if !newV.IsArray() {
+		return false, failureError(typeMismatch(path, "[]", newV))
+	}
+
+	oldArr, newArr := oldV.ArrayValue(), newV.ArrayValue()
+	if len(oldArr) != len(newArr) {
+		return true, nil
+	}
+	matched := make([]bool, len(newArr))
+	for _, oldE := range oldArr {
+		found := false
+		for i, newE := range newArr {
+			if !matched[i] && plainValue(oldE).DeepEquals(plainValue(newE)) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// diffProperties diffs the given old and new property maps according to the given schema. It returns true if any
+// properties changed, the paths of any changed properties that require replacement, and a detailed diff that
+// describes the change to each property. Changes to immutableWhenSet properties are errors.
+func diffProperties(olds, news resource.PropertyMap,
+	schema interface{}) (bool, []string, map[string]*pulumirpc.PropertyDiff, error) {
+
+	d := &differ{detailedDiff: map[string]*pulumirpc.PropertyDiff{}, enforceImmutable: true}
 	oldV, newV := resource.NewObjectProperty(olds), resource.NewObjectProperty(news)
 	changed, err := d.diffProperty("", oldV, newV, reflect.TypeOf(schema))
 	if err != nil {
-		return false, nil, err
+		return false, nil, nil, err
+	}
+	if len(d.immutables) != 0 {
+		return false, nil, nil, errors.Errorf("properties cannot be changed once set: %v",
+			strings.Join(d.immutables, ", "))
+	}
+	return changed, d.replaces, d.detailedDiff, nil
+}
+
+// diffLiveProperties diffs the live properties of a resource against its inputs according to the given schema, and
+// returns a detailed diff that describes the drift in each property. Unlike diffProperties, drift in immutableWhenSet
+// properties is reported like any other.
+func diffLiveProperties(live, inputs resource.PropertyMap,
+	schema interface{}) (map[string]*pulumirpc.PropertyDiff, error) {
+
+	d := &differ{detailedDiff: map[string]*pulumirpc.PropertyDiff{}}
+	liveV, inputsV := resource.NewObjectProperty(live), resource.NewObjectProperty(inputs)
+	if _, err := d.diffProperty("", liveV, inputsV, reflect.TypeOf(schema)); err != nil {
+		return nil, err
 	}
-	return changed, d.replaces, nil
+	return d.detailedDiff, nil
 }
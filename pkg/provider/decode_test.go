@@ -0,0 +1,1088 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetadata struct {
+	Name   string            `pulumi:"name,forceNew"`
+	Labels map[string]string `pulumi:"labels,optional"`
+}
+
+// TestLimits is exported so that pointers to it can be allocated when decoding embedded fields.
+type TestLimits struct {
+	Memory string `pulumi:"memory,optional"`
+}
+
+type testEmbedding struct {
+	testMetadata
+	*TestLimits
+	Image string `pulumi:"image"`
+	// Name shadows the promoted field of the same name.
+	Name string `pulumi:"name,optional"`
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":   "echo",
+		"labels": map[string]interface{}{"team": "a"},
+		"memory": "128Mi",
+		"image":  "functions/alpine",
+	})
+
+	failures, err := checkProperties(props, testEmbedding{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testEmbedding
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, "echo", decoded.Name)
+	assert.Equal(t, "", decoded.testMetadata.Name)
+	assert.Equal(t, map[string]string{"team": "a"}, decoded.Labels)
+	if assert.NotNil(t, decoded.TestLimits) {
+		assert.Equal(t, "128Mi", decoded.Memory)
+	}
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, resource.NewObjectProperty(props).DeepEquals(resource.NewObjectProperty(encoded)))
+
+	names, err := forceNewProperties(testEmbedding{})
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestNilEmbeddedStruct(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "functions/alpine"})
+
+	var decoded testEmbedding
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Nil(t, decoded.TestLimits)
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, encoded["memory"].IsNull())
+}
+
+type testAmbiguous struct {
+	testMetadata
+	Other testMetadata
+	testDuplicate
+}
+
+type testDuplicate struct {
+	Labels map[string]string `pulumi:"labels"`
+}
+
+func TestAmbiguousEmbeddedStructs(t *testing.T) {
+	_, err := structFields(reflect.TypeOf(testAmbiguous{}))
+	assert.Error(t, err)
+}
+
+type testExtraSpec struct {
+	Extra interface{}            `pulumi:"extra,optional"`
+	Spec  map[string]interface{} `pulumi:"spec,optional"`
+}
+
+func TestArbitraryValues(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"extra": []interface{}{"a", 1.0, true},
+		"spec": map[string]interface{}{
+			"replicas": 2.0,
+			"nested":   map[string]interface{}{"key": "value"},
+		},
+	})
+
+	failures, err := checkProperties(props, testExtraSpec{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testExtraSpec
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, []interface{}{"a", 1.0, true}, decoded.Extra)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, decoded.Spec["nested"])
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, resource.NewObjectProperty(props).DeepEquals(resource.NewObjectProperty(encoded)))
+
+	news := props.Copy()
+	news["spec"] = resource.NewPropertyValue(map[string]interface{}{"replicas": 3.0})
+	changed, _, detailedDiff, err := diffProperties(props, news, testExtraSpec{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "spec.replicas")
+	assert.Contains(t, detailedDiff, "spec.nested")
+}
+
+type testTimeouts struct {
+	Timeout time.Duration  `pulumi:"timeout"`
+	Delay   *time.Duration `pulumi:"delay,optional"`
+}
+
+func TestDurations(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"timeout": "30s", "delay": "2m"})
+
+	failures, err := checkProperties(props, testTimeouts{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testTimeouts
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, 30*time.Second, decoded.Timeout)
+	if assert.NotNil(t, decoded.Delay) {
+		assert.Equal(t, 2*time.Minute, *decoded.Delay)
+	}
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "30s", encoded["timeout"].StringValue())
+	assert.Equal(t, "2m0s", encoded["delay"].StringValue())
+
+	changed, _, _, err := diffProperties(props, encoded, testTimeouts{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{"timeout": "soon"})
+	failures, err = checkProperties(invalid, testTimeouts{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "timeout", failures[0].Property)
+	}
+}
+
+type testPolicy struct {
+	PullPolicy string   `pulumi:"imagePullPolicy,optional,enum=Always|IfNotPresent|Never"`
+	Protocols  []string `pulumi:"protocols,optional,enum=http|grpc"`
+}
+
+func TestEnumConstraint(t *testing.T) {
+	valid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"imagePullPolicy": "IfNotPresent",
+		"protocols":       []interface{}{"http", "grpc"},
+	})
+	failures, err := checkProperties(valid, testPolicy{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"imagePullPolicy": "Sometimes",
+		"protocols":       []interface{}{"http", "tcp"},
+	})
+	failures, err = checkProperties(invalid, testPolicy{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 2) {
+		properties := []string{failures[0].Property, failures[1].Property}
+		assert.Contains(t, properties, "imagePullPolicy")
+		assert.Contains(t, properties, "protocols[1]")
+	}
+}
+
+type testScaling struct {
+	Name     string  `pulumi:"name,pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"`
+	Replicas int     `pulumi:"replicas,optional,min=0,max=20"`
+	Factor   float64 `pulumi:"factor,optional,min=0.5"`
+}
+
+func TestRangeAndPatternConstraints(t *testing.T) {
+	valid := resource.NewPropertyMapFromMap(map[string]interface{}{"name": "echo-1", "replicas": 20, "factor": 0.5})
+	failures, err := checkProperties(valid, testScaling{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{"name": "Echo_1", "replicas": -1, "factor": 0.1})
+	failures, err = checkProperties(invalid, testScaling{})
+	assert.NoError(t, err)
+	var properties []string
+	for _, f := range failures {
+		properties = append(properties, f.Property)
+	}
+	assert.ElementsMatch(t, []string{"name", "replicas", "factor"}, properties)
+}
+
+func TestInvalidConstraintTag(t *testing.T) {
+	type invalid struct {
+		Replicas int `pulumi:"replicas,min=none"`
+	}
+	_, err := checkProperties(resource.PropertyMap{}, invalid{})
+	assert.Error(t, err)
+}
+
+type testDefaults struct {
+	Policy   string            `pulumi:"policy,optional,default=IfNotPresent"`
+	Replicas *int              `pulumi:"replicas,optional,default=1"`
+	Labels   map[string]string `pulumi:"labels,optional,default={\"tier\":\"web\"}"`
+	Limits   *testLimitsSpec   `pulumi:"limits,optional"`
+}
+
+type testLimitsSpec struct {
+	Memory string `pulumi:"memory,optional,default=128Mi"`
+}
+
+func TestApplyDefaults(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": "Always",
+		"limits": map[string]interface{}{},
+	})
+	assert.NoError(t, applyDefaults(props, testDefaults{}))
+
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy":   "Always",
+		"replicas": 1.0,
+		"labels":   map[string]interface{}{"tier": "web"},
+		"limits":   map[string]interface{}{"memory": "128Mi"},
+	})
+	assert.True(t, resource.NewObjectProperty(expected).DeepEquals(resource.NewObjectProperty(props)))
+}
+
+func TestDefaultRequiresOptional(t *testing.T) {
+	type invalid struct {
+		Policy string `pulumi:"policy,default=Always"`
+	}
+	assert.Error(t, applyDefaults(resource.PropertyMap{}, invalid{}))
+}
+
+type testCredentials struct {
+	Username string `pulumi:"username"`
+	Password string `pulumi:"password,optional,secret"`
+}
+
+type testAccount struct {
+	Credentials *testCredentials  `pulumi:"credentials,optional"`
+	Mirrors     []testCredentials `pulumi:"mirrors,optional"`
+}
+
+func TestSecretFields(t *testing.T) {
+	encoded, err := encodeProperties(testCredentials{Username: "admin", Password: "hunter2"})
+	assert.NoError(t, err)
+	assert.False(t, encoded["username"].IsSecret())
+	if assert.True(t, encoded["password"].IsSecret()) {
+		assert.Equal(t, "hunter2", encoded["password"].SecretValue().Element.StringValue())
+	}
+
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{"username": "admin", "password": "hunter2"})
+	changed, _, _, err := diffProperties(encoded, inputs, testCredentials{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	inputs["password"] = resource.NewStringProperty("correct horse")
+	changed, _, detailedDiff, err := diffProperties(encoded, inputs, testCredentials{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "password")
+
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"username": "admin", "password": "hunter2"})
+	assert.NoError(t, markSecrets(props, testCredentials{}))
+	assert.True(t, props["password"].IsSecret())
+	assert.False(t, props["username"].IsSecret())
+
+	nested := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"credentials": map[string]interface{}{"username": "admin", "password": "hunter2"},
+		"mirrors":     []interface{}{map[string]interface{}{"username": "ci", "password": "swordfish"}},
+	})
+	assert.NoError(t, markSecrets(nested, testAccount{}))
+	credentials := nested["credentials"].ObjectValue()
+	assert.True(t, credentials["password"].IsSecret())
+	assert.False(t, credentials["username"].IsSecret())
+	assert.True(t, nested["mirrors"].ArrayValue()[0].ObjectValue()["password"].IsSecret())
+}
+
+func TestDecodePartialProperties(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":   "echo",
+		"labels": map[string]interface{}{"team": "a"},
+	})
+	props["image"] = resource.MakeComputed(resource.NewStringProperty(""))
+	props["labels"].ObjectValue()["owner"] = resource.MakeComputed(resource.NewStringProperty(""))
+
+	var decoded testEmbedding
+	assert.Error(t, decodeProperties(props, &decoded))
+
+	unknowns, err := decodePartialProperties(props, &decoded)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"image", "labels.owner"}, unknowns)
+	assert.Equal(t, "echo", decoded.Name)
+	assert.Equal(t, "", decoded.Image)
+	assert.Equal(t, "a", decoded.Labels["team"])
+}
+
+type testDeprecated struct {
+	Image    string           `pulumi:"image"`
+	Network  string           `pulumi:"network,optional,deprecated=networks are managed by the gateway"`
+	Scaling  *testDeprecation `pulumi:"scaling,optional"`
+	Replicas int              `pulumi:"replicas,optional,min=0"`
+}
+
+type testDeprecation struct {
+	Factor int `pulumi:"factor,optional,deprecated=use scaling.target instead"`
+}
+
+func TestDeprecatedProperties(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"image":    "functions/alpine",
+		"network":  "func_functions",
+		"scaling":  map[string]interface{}{"factor": 20},
+		"replicas": -1,
+	})
+
+	failures, warnings, err := checkPropertiesWithWarnings(props, testDeprecated{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "replicas", failures[0].Property)
+	}
+	assert.ElementsMatch(t, []string{
+		"property network is deprecated: networks are managed by the gateway",
+		"property scaling.factor is deprecated: use scaling.target instead",
+	}, warnings)
+
+	delete(props, "network")
+	delete(props, "scaling")
+	_, warnings, err = checkPropertiesWithWarnings(props, testDeprecated{})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+// testQuantity is a memory quantity such as "128Mi", held as a number of bytes.
+type testQuantity int64
+
+func (q testQuantity) MarshalProperty() (resource.PropertyValue, error) {
+	if q%(1<<20) == 0 {
+		return resource.NewStringProperty(fmt.Sprintf("%dMi", q>>20)), nil
+	}
+	return resource.NewStringProperty(strconv.FormatInt(int64(q), 10)), nil
+}
+
+func (q *testQuantity) UnmarshalProperty(v resource.PropertyValue) error {
+	if !v.IsString() {
+		return fmt.Errorf("expected a quantity, received a %v", v.TypeString())
+	}
+	s, scale := v.StringValue(), int64(1)
+	if strings.HasSuffix(s, "Mi") {
+		s, scale = strings.TrimSuffix(s, "Mi"), 1<<20
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", v.StringValue())
+	}
+	*q = testQuantity(n * scale)
+	return nil
+}
+
+type testResources struct {
+	Memory testQuantity  `pulumi:"memory"`
+	Limit  *testQuantity `pulumi:"limit,optional"`
+}
+
+func TestPropertyMarshalers(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"memory": "128Mi", "limit": "1048577"})
+
+	failures, err := checkProperties(props, testResources{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testResources
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, testQuantity(128<<20), decoded.Memory)
+	if assert.NotNil(t, decoded.Limit) {
+		assert.Equal(t, testQuantity(1<<20+1), *decoded.Limit)
+	}
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, resource.NewObjectProperty(props).DeepEquals(resource.NewObjectProperty(encoded)))
+
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"memory": "134217728", "limit": "1048577"})
+	changed, _, _, err := diffProperties(props, news, testResources{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{"memory": "lots"})
+	failures, err = checkProperties(invalid, testResources{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "memory", failures[0].Property)
+		assert.Equal(t, `invalid quantity "lots"`, failures[0].Reason)
+	}
+}
+
+func TestDecodeReportsAllFailures(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"labels": map[string]interface{}{"team": 1},
+		"memory": true,
+	})
+
+	var decoded testEmbedding
+	err := decodeProperties(props, &decoded)
+	if assert.Error(t, err) {
+		failures := err.(*decodeError).failures
+		var properties []string
+		for _, f := range failures {
+			properties = append(properties, f.Property)
+		}
+		assert.ElementsMatch(t, []string{"", "labels.team", "memory"}, properties)
+		assert.Len(t, strings.Split(err.Error(), "; "), 3)
+	}
+}
+
+type testSecrets struct {
+	Secrets []string `pulumi:"secrets,optional,set"`
+	Args    []string `pulumi:"args,optional"`
+}
+
+func TestSetDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"secrets": []interface{}{"a", "b", "b"},
+		"args":    []interface{}{"-v", "-q"},
+	})
+
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"secrets": []interface{}{"b", "a", "b"},
+		"args":    []interface{}{"-v", "-q"},
+	})
+	changed, _, _, err := diffProperties(olds, news, testSecrets{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	news["secrets"] = resource.NewPropertyValue([]interface{}{"a", "a", "b"})
+	news["args"] = resource.NewPropertyValue([]interface{}{"-q", "-v"})
+	changed, _, detailedDiff, err := diffProperties(olds, news, testSecrets{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "secrets")
+	assert.Contains(t, detailedDiff, "args[0]")
+}
+
+type testEndpoint struct {
+	URL     string `pulumi:"url,forceNew"`
+	Timeout int    `pulumi:"timeout,optional"`
+}
+
+type testRouting struct {
+	Primary   *testEndpoint           `pulumi:"primary,optional"`
+	Fallbacks []testEndpoint          `pulumi:"fallbacks,optional"`
+	Regions   map[string]testEndpoint `pulumi:"regions,optional"`
+}
+
+func TestNestedForceNew(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"fallbacks": []interface{}{map[string]interface{}{"url": "http://a"}},
+		"regions":   map[string]interface{}{"eu": map[string]interface{}{"url": "http://eu", "timeout": 1}},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"primary": map[string]interface{}{"url": "http://p"},
+		"fallbacks": []interface{}{
+			map[string]interface{}{"url": "http://b"},
+			map[string]interface{}{"url": "http://c"},
+		},
+		"regions": map[string]interface{}{
+			"eu": map[string]interface{}{"url": "http://eu", "timeout": 2},
+			"us": map[string]interface{}{"url": "http://us"},
+		},
+	})
+
+	changed, replaces, detailedDiff, err := diffProperties(olds, news, testRouting{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.ElementsMatch(t, []string{
+		"primary.url", "fallbacks[0].url", "fallbacks[1].url", "regions.us.url",
+	}, replaces)
+	if assert.Contains(t, detailedDiff, "primary") {
+		assert.Equal(t, pulumirpc.PropertyDiff_ADD_REPLACE, detailedDiff["primary"].Kind)
+	}
+}
+
+type testFunctionSpec struct {
+	EnvVars  map[string]string `pulumi:"envVars,optional"`
+	Args     []string          `pulumi:"args,optional"`
+	Endpoint *testEndpoint     `pulumi:"endpoint,optional,forceNew"`
+}
+
+func TestElementDiffs(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"envVars":  map[string]interface{}{"a": "1", "b": "2", "c": "3"},
+		"args":     []interface{}{"-v", "-q", "-x"},
+		"endpoint": map[string]interface{}{"url": "http://a", "timeout": 1},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"envVars":  map[string]interface{}{"a": "1", "b": "20", "d": "4"},
+		"args":     []interface{}{"-v", "-Q"},
+		"endpoint": map[string]interface{}{"url": "http://a", "timeout": 2},
+	})
+
+	changed, replaces, detailedDiff, err := diffProperties(olds, news, testFunctionSpec{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"endpoint"}, replaces)
+
+	kinds := map[string]pulumirpc.PropertyDiff_Kind{}
+	for path, diff := range detailedDiff {
+		kinds[path] = diff.Kind
+	}
+	assert.Equal(t, map[string]pulumirpc.PropertyDiff_Kind{
+		"envVars.b":        pulumirpc.PropertyDiff_UPDATE,
+		"envVars.c":        pulumirpc.PropertyDiff_DELETE,
+		"envVars.d":        pulumirpc.PropertyDiff_ADD,
+		"args[1]":          pulumirpc.PropertyDiff_UPDATE,
+		"args[2]":          pulumirpc.PropertyDiff_DELETE,
+		"endpoint.timeout": pulumirpc.PropertyDiff_UPDATE_REPLACE,
+	}, kinds)
+}
+
+type testRenamed struct {
+	Image    string `pulumi:"image"`
+	FProcess string `pulumi:"fprocess,optional,alias=envProcess"`
+}
+
+func TestPropertyAliases(t *testing.T) {
+	legacy := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine", "envProcess": "cat"})
+
+	failures, err := checkProperties(legacy, testRenamed{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testRenamed
+	assert.NoError(t, decodeProperties(legacy, &decoded))
+	assert.Equal(t, "cat", decoded.FProcess)
+
+	renamed := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine", "fprocess": "cat"})
+	changed, _, _, err := diffProperties(legacy, renamed, testRenamed{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	renamed["fprocess"] = resource.NewStringProperty("sha512sum")
+	changed, _, detailedDiff, err := diffProperties(legacy, renamed, testRenamed{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "fprocess")
+
+	both := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"image": "alpine", "envProcess": "cat", "fprocess": "cat",
+	})
+	failures, err = checkProperties(both, testRenamed{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "envProcess", failures[0].Property)
+	}
+}
+
+type testStackFunction struct {
+	Image    string            `pulumi:"image"`
+	EnvVars  map[string]string `pulumi:"envVars,optional"`
+	Limits   *testLimitsSpec   `pulumi:"limits,optional"`
+	ReadOnly bool              `pulumi:"readOnlyRootFilesystem,optional"`
+}
+
+func TestFoldPropertyKeys(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"Image":                     "alpine",
+		"env_vars":                  map[string]interface{}{"write_debug": "true"},
+		"limits":                    map[string]interface{}{"MEMORY": "128Mi"},
+		"read-only-root-filesystem": true,
+	})
+	assert.NoError(t, foldPropertyKeys(props, testStackFunction{}))
+
+	var decoded testStackFunction
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, "alpine", decoded.Image)
+	// Map keys are data rather than property names, so they are left as-is.
+	assert.Equal(t, map[string]string{"write_debug": "true"}, decoded.EnvVars)
+	if assert.NotNil(t, decoded.Limits) {
+		assert.Equal(t, "128Mi", decoded.Limits.Memory)
+	}
+	assert.True(t, decoded.ReadOnly)
+
+	conflicting := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine", "IMAGE": "busybox"})
+	assert.Error(t, foldPropertyKeys(conflicting, testStackFunction{}))
+}
+
+type testBinary struct {
+	Value []byte          `pulumi:"value,optional"`
+	Spec  json.RawMessage `pulumi:"spec,optional"`
+}
+
+func TestBytesAndRawMessages(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"value": base64.StdEncoding.EncodeToString([]byte{0, 1, 0xff}),
+		"spec":  map[string]interface{}{"replicas": 2.0, "ports": []interface{}{8080.0}},
+	})
+
+	failures, err := checkProperties(props, testBinary{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testBinary
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.Equal(t, []byte{0, 1, 0xff}, decoded.Value)
+	assert.JSONEq(t, `{"replicas": 2, "ports": [8080]}`, string(decoded.Spec))
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, resource.NewObjectProperty(props).DeepEquals(resource.NewObjectProperty(encoded)))
+
+	news := props.Copy()
+	news["spec"] = resource.NewPropertyValue(map[string]interface{}{"replicas": 3.0, "ports": []interface{}{8080.0}})
+	changed, _, detailedDiff, err := diffProperties(props, news, testBinary{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "spec")
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{"value": "not base64!"})
+	failures, err = checkProperties(invalid, testBinary{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "value", failures[0].Property)
+	}
+}
+
+type testRoute struct {
+	Path    string `pulumi:"path"`
+	Timeout string `pulumi:"timeout,optional,default=30s"`
+	Host    string `pulumi:"host,optional,forceNew"`
+}
+
+type testRoutes struct {
+	Routes   []*testRoute          `pulumi:"routes,optional"`
+	ByName   map[string]*testRoute `pulumi:"byName,optional"`
+	Fallback **testRoute           `pulumi:"fallback,optional"`
+}
+
+func TestPointersInSlicesAndMaps(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"routes":   []interface{}{map[string]interface{}{"path": "/a"}, nil},
+		"byName":   map[string]interface{}{"b": map[string]interface{}{"path": "/b"}, "none": nil},
+		"fallback": map[string]interface{}{"path": "/"},
+	})
+	assert.NoError(t, applyDefaults(props, testRoutes{}))
+	assert.Equal(t, "30s", props["routes"].ArrayValue()[0].ObjectValue()["timeout"].StringValue())
+	assert.Equal(t, "30s", props["byName"].ObjectValue()["b"].ObjectValue()["timeout"].StringValue())
+
+	failures, err := checkProperties(props, testRoutes{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testRoutes
+	assert.NoError(t, decodeProperties(props, &decoded))
+	if assert.Len(t, decoded.Routes, 2) {
+		assert.Equal(t, &testRoute{Path: "/a", Timeout: "30s"}, decoded.Routes[0])
+		assert.Nil(t, decoded.Routes[1])
+	}
+	assert.Equal(t, &testRoute{Path: "/b", Timeout: "30s"}, decoded.ByName["b"])
+	assert.Nil(t, decoded.ByName["none"])
+	if assert.NotNil(t, decoded.Fallback) && assert.NotNil(t, *decoded.Fallback) {
+		assert.Equal(t, "/", (*decoded.Fallback).Path)
+	}
+
+	encoded, err := encodeProperties(decoded)
+	assert.NoError(t, err)
+	assert.True(t, encoded["routes"].ArrayValue()[1].IsNull())
+	assert.True(t, encoded["byName"].ObjectValue()["none"].IsNull())
+
+	// A missing required property within an element is reported at the element's path.
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"routes": []interface{}{nil, map[string]interface{}{"timeout": "1s"}},
+		"byName": "routes",
+	})
+	failures, err = checkProperties(invalid, testRoutes{})
+	assert.NoError(t, err)
+	var properties []string
+	for _, f := range failures {
+		properties = append(properties, f.Property)
+	}
+	assert.ElementsMatch(t, []string{"routes[1]", "byName"}, properties)
+
+	// Setting a pointer element that holds a forceNew property replaces the resource; unsetting a property by
+	// setting it to null is not a change.
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"routes": []interface{}{nil}})
+	olds["fallback"] = resource.NewNullProperty()
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"routes": []interface{}{map[string]interface{}{"path": "/a", "host": "example.com"}},
+	})
+	changed, replaces, detailedDiff, err := diffProperties(olds, news, testRoutes{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"routes[0].host"}, replaces)
+	if assert.Contains(t, detailedDiff, "routes[0]") {
+		assert.Equal(t, pulumirpc.PropertyDiff_UPDATE_REPLACE, detailedDiff["routes[0]"].Kind)
+	}
+	assert.NotContains(t, detailedDiff, "fallback")
+}
+
+func BenchmarkCheckFunction(b *testing.B) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service":     "echo",
+		"image":       "ghcr.io/openfaas/alpine:latest",
+		"envVars":     map[string]interface{}{"fprocess": "cat", "write_debug": "true"},
+		"labels":      map[string]interface{}{"com.openfaas.scale.min": "1"},
+		"annotations": map[string]interface{}{"topic": "events"},
+		"secrets":     []interface{}{"api-key"},
+	})
+	for i := 0; i < b.N; i++ {
+		if _, err := checkProperties(props, function{}); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, _, err := diffProperties(props, props, function{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type testRelated struct {
+	ScaleMin     *int   `pulumi:"scaleMin,optional,requiredWith=scaleMax"`
+	ScaleMax     *int   `pulumi:"scaleMax,optional"`
+	RegistryAuth string `pulumi:"registryAuth,optional"`
+	PullSecret   string `pulumi:"pullSecret,optional,conflictsWith=registryAuth"`
+}
+
+type testBadRelation struct {
+	ScaleMin int `pulumi:"scaleMin,optional,requiredWith=scaleMaximum"`
+}
+
+func TestPropertyRelationships(t *testing.T) {
+	failures, err := checkProperties(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"scaleMin": 1, "scaleMax": 5, "registryAuth": "secret",
+	}), testRelated{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	failures, err = checkProperties(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"scaleMin": 1, "registryAuth": "secret", "pullSecret": "registry",
+	}), testRelated{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 2) {
+		assert.Equal(t, "scaleMin", failures[0].Property)
+		assert.Equal(t, "scaleMax must also be specified when scaleMin is specified", failures[0].Reason)
+		assert.Equal(t, "pullSecret", failures[1].Property)
+		assert.Equal(t, "registryAuth and pullSecret cannot both be specified", failures[1].Reason)
+	}
+
+	// Unknown values may turn out to be unset, so they do not conflict.
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"pullSecret": "registry"})
+	props["registryAuth"] = resource.MakeComputed(resource.NewStringProperty(""))
+	failures, err = checkProperties(props, testRelated{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	_, err = checkProperties(resource.PropertyMap{}, testBadRelation{})
+	assert.Error(t, err)
+}
+
+type testCollections struct {
+	Labels   map[string]string `pulumi:"labels,optional"`
+	Secrets  []string          `pulumi:"secrets,optional"`
+	Args     []string          `pulumi:"args,optional,strict"`
+	Protocol string            `pulumi:"protocol,optional,default=http"`
+}
+
+func TestDiffEmptyCollections(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"labels": map[string]interface{}{}, "secrets": []interface{}{}, "protocol": "http",
+	})
+	changed, _, detailedDiff, err := diffProperties(olds, news, testCollections{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, detailedDiff)
+
+	changed, _, _, err = diffProperties(news, olds, testCollections{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	news = resource.NewPropertyMapFromMap(map[string]interface{}{"args": []interface{}{}, "protocol": "https"})
+	changed, _, detailedDiff, err = diffProperties(olds, news, testCollections{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	if assert.Len(t, detailedDiff, 2) {
+		assert.Equal(t, pulumirpc.PropertyDiff_ADD, detailedDiff["args"].Kind)
+		assert.Equal(t, pulumirpc.PropertyDiff_UPDATE, detailedDiff["protocol"].Kind)
+	}
+}
+
+type testNumbers struct {
+	Replicas uint8   `pulumi:"replicas,optional"`
+	Offset   int16   `pulumi:"offset,optional"`
+	Count    int64   `pulumi:"count,optional"`
+	Ratio    float32 `pulumi:"ratio,optional"`
+}
+
+func TestNumericBounds(t *testing.T) {
+	valid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"replicas": 255, "offset": -32768, "count": 1 << 53, "ratio": 0.5,
+	})
+	failures, err := checkProperties(valid, testNumbers{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testNumbers
+	assert.NoError(t, decodeProperties(valid, &decoded))
+	assert.Equal(t, testNumbers{Replicas: 255, Offset: -32768, Count: 1 << 53, Ratio: 0.5}, decoded)
+
+	invalid := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"replicas": 256, "offset": 1.5, "count": 1e19, "ratio": 1e39,
+	})
+	failures, err = checkProperties(invalid, testNumbers{})
+	assert.NoError(t, err)
+	reasons := map[string]string{}
+	for _, f := range failures {
+		reasons[string(f.Property)] = f.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"replicas": "expected a value that fits in a uint8, received 256",
+		"offset":   "expected an integer, received 1.5",
+		"count":    "expected a value that fits in an int64, received 1e+19",
+		"ratio":    "expected a value that fits in a float32, received 1e+39",
+	}, reasons)
+
+	err = decodeProperties(invalid, &decoded)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "expected a value that fits in a uint8, received 256")
+	}
+}
+
+type testStrings struct {
+	SkipAwait bool              `pulumi:"skipAwait,optional"`
+	Replicas  *int              `pulumi:"replicas,optional"`
+	Weights   []float64         `pulumi:"weights,optional"`
+	Limits    map[string]int    `pulumi:"limits,optional"`
+	Labels    map[string]string `pulumi:"labels,optional"`
+}
+
+func TestCoerceStrings(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"skipAwait": "true",
+		"weights":   []interface{}{"0.5", 2},
+		"limits":    map[string]interface{}{"memory": "128"},
+		"labels":    map[string]interface{}{"tier": "3"},
+	})
+	props["replicas"] = resource.MakeSecret(resource.NewStringProperty("3"))
+
+	warnings, err := coerceStrings(props, testStrings{})
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 4)
+	assert.Contains(t, warnings, "property skipAwait: converted a string to a bool")
+	assert.Contains(t, warnings, "property weights[0]: converted a string to a number")
+	if assert.True(t, props["replicas"].IsSecret()) {
+		assert.Equal(t, resource.NewNumberProperty(3), props["replicas"].SecretValue().Element)
+	}
+
+	delete(props, "replicas")
+	failures, err := checkProperties(props, testStrings{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testStrings
+	assert.NoError(t, decodeProperties(props, &decoded))
+	assert.True(t, decoded.SkipAwait)
+	assert.Equal(t, []float64{0.5, 2}, decoded.Weights)
+	assert.Equal(t, map[string]int{"memory": 128}, decoded.Limits)
+	assert.Equal(t, map[string]string{"tier": "3"}, decoded.Labels)
+
+	// Strings that do not parse are left for the checker to report.
+	props = resource.NewPropertyMapFromMap(map[string]interface{}{"skipAwait": "sometimes"})
+	warnings, err = coerceStrings(props, testStrings{})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	failures, err = checkProperties(props, testStrings{})
+	assert.NoError(t, err)
+	assert.Len(t, failures, 1)
+}
+
+func TestSecretValues(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{"name": "echo", "factor": 1})
+	props["replicas"] = resource.MakeSecret(resource.NewNumberProperty(30))
+
+	failures, err := checkProperties(props, testScaling{})
+	assert.NoError(t, err)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "replicas", failures[0].Property)
+		assert.Equal(t, "expected a value of at most 20, received 30", failures[0].Reason)
+	}
+
+	props["replicas"] = resource.MakeSecret(resource.NewNumberProperty(3))
+	failures, err = checkProperties(props, testScaling{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testScaling
+	secrets, err := decodeSecretProperties(props, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, decoded.Replicas)
+	assert.Equal(t, []string{"replicas"}, secrets)
+
+	outputs, err := encodeProperties(decoded)
+	if assert.NoError(t, err) {
+		markSecretPaths(outputs, secrets)
+		assert.True(t, outputs["replicas"].IsSecret())
+		assert.False(t, outputs["name"].IsSecret())
+	}
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"name": "echo", "factor": 1, "replicas": 3})
+	changed, _, _, err := diffProperties(olds, props, testScaling{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+type testHandler struct {
+	Code    *resource.Asset  `pulumi:"code,optional"`
+	Bundle  resource.Archive `pulumi:"bundle,optional"`
+	Secrets []resource.Asset `pulumi:"secrets,optional"`
+}
+
+func TestAssetsAndArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "handler.py")
+	if !assert.NoError(t, ioutil.WriteFile(path, []byte("print('hello')"), 0600)) {
+		return
+	}
+
+	text, err := resource.NewTextAsset("print('hello')")
+	if !assert.NoError(t, err) {
+		return
+	}
+	props := resource.PropertyMap{
+		"code":    resource.NewAssetProperty(text),
+		"bundle":  resource.NewStringProperty("https://example.test/bundle.zip"),
+		"secrets": resource.NewArrayProperty([]resource.PropertyValue{resource.NewStringProperty(path)}),
+	}
+	failures, err := checkProperties(props, testHandler{})
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	var decoded testHandler
+	assert.NoError(t, decodeProperties(props, &decoded))
+	if assert.NotNil(t, decoded.Code) {
+		assert.Equal(t, "print('hello')", decoded.Code.Text)
+	}
+	assert.Equal(t, "https://example.test/bundle.zip", decoded.Bundle.URI)
+	if assert.Len(t, decoded.Secrets, 1) {
+		assert.Equal(t, path, decoded.Secrets[0].Path)
+	}
+
+	encoded, err := encodeProperties(testHandler{Code: text})
+	if assert.NoError(t, err) {
+		assert.True(t, encoded["code"].IsAsset())
+		assert.True(t, encoded["bundle"].IsNull())
+	}
+
+	// Assets with the same contents do not differ, however they are specified.
+	news := props.Copy()
+	news["code"] = resource.NewStringProperty(path)
+	changed, _, _, err := diffProperties(props, news, testHandler{})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	news["bundle"] = resource.NewStringProperty("https://example.test/bundle-v2.zip")
+	changed, _, detailedDiff, err := diffProperties(props, news, testHandler{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Contains(t, detailedDiff, "bundle")
+
+	failures, err = checkProperties(resource.PropertyMap{"code": resource.NewNumberProperty(1)}, testHandler{})
+	assert.NoError(t, err)
+	assert.Len(t, failures, 1)
+}
+
+type testImmutable struct {
+	Image  string `pulumi:"image"`
+	Volume string `pulumi:"volume,optional,immutableWhenSet"`
+}
+
+func TestImmutableWhenSet(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine"})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine", "volume": "data"})
+
+	// The property may be set once.
+	changed, replaces, _, err := diffProperties(olds, news, testImmutable{})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Empty(t, replaces)
+
+	// It may not be changed or unset afterwards.
+	modified := resource.NewPropertyMapFromMap(map[string]interface{}{"image": "alpine", "volume": "logs"})
+	_, _, _, err = diffProperties(news, modified, testImmutable{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "properties cannot be changed once set: volume", err.Error())
+	}
+	_, _, _, err = diffProperties(news, olds, testImmutable{})
+	assert.Error(t, err)
+
+	// Unknown values may turn out to be unchanged.
+	unknown := news.Copy()
+	unknown["volume"] = resource.MakeComputed(resource.NewStringProperty(""))
+	_, _, _, err = diffProperties(news, unknown, testImmutable{})
+	assert.NoError(t, err)
+
+	// Drift is reported like any other change.
+	detailedDiff, err := diffLiveProperties(modified, news, testImmutable{})
+	assert.NoError(t, err)
+	assert.Contains(t, detailedDiff, "volume")
+}
+
+type testListener struct {
+	Path    string `pulumi:"path,pattern=^/"`
+	Port    int    `pulumi:"port,optional,min=1,max=65535"`
+	Timeout string `pulumi:"timeout,optional"`
+}
+
+type testListeners struct {
+	Endpoints []testListener `pulumi:"endpoints"`
+}
+
+func TestFailuresInArraysOfStructs(t *testing.T) {
+	props := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"endpoints": []interface{}{
+			map[string]interface{}{"path": "a", "port": 0},
+			map[string]interface{}{"path": "/b"},
+			map[string]interface{}{"port": 8080, "timeout": 30},
+			"/d",
+		},
+	})
+
+	failures, err := checkProperties(props, testListeners{})
+	assert.NoError(t, err)
+	var properties []string
+	for _, f := range failures {
+		properties = append(properties, string(f.Property))
+	}
+	assert.Equal(t, []string{
+		"endpoints[0].path", "endpoints[0].port", "endpoints[2]", "endpoints[2].timeout", "endpoints[3]",
+	}, properties)
+
+	var decoded testListeners
+	err = decodeProperties(props, &decoded)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "endpoints[2].timeout")
+		assert.Contains(t, err.Error(), "endpoints[3]")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// deprecation describes a deprecated use of a resource's properties.
+type deprecation struct {
+	// applies returns true if the given inputs use the deprecated form.
+	applies func(inputs resource.PropertyMap) bool
+	// message tells the user how to migrate away from the deprecated form.
+	message string
+	// upgrade, if non-nil, rewrites the deprecated form in place so that it continues to work.
+	upgrade func(inputs resource.PropertyMap) error
+}
+
+// isArrayProperty returns a function that checks whether the property with the given key is an array.
+func isArrayProperty(key resource.PropertyKey) func(resource.PropertyMap) bool {
+	return func(inputs resource.PropertyMap) bool {
+		return inputs[key].IsArray()
+	}
+}
+
+// upgradeListToMap returns a function that converts the property with the given key from a list of "key=value"
+// strings to a map.
+func upgradeListToMap(key resource.PropertyKey) func(resource.PropertyMap) error {
+	return func(inputs resource.PropertyMap) error {
+		return listToMap(inputs, key)
+	}
+}
+
+// functionDeprecations lists the deprecated uses of function properties.
+var functionDeprecations = []deprecation{
+	{
+		applies: isArrayProperty("labels"),
+		message: "specifying labels as a list of \"key=value\" strings is deprecated and will be removed in a " +
+			"future release; specify labels as a map instead",
+		upgrade: upgradeListToMap("labels"),
+	},
+	{
+		applies: isArrayProperty("annotations"),
+		message: "specifying annotations as a list of \"key=value\" strings is deprecated and will be removed in a " +
+			"future release; specify annotations as a map instead",
+		upgrade: upgradeListToMap("annotations"),
+	},
+}
+
+// checkDeprecations warns the user about any deprecated uses of the given resource inputs and upgrades them where
+// possible. Inputs that cannot be upgraded are left as-is so that the schema check can report them.
+func (p *faasProvider) checkDeprecations(ctx context.Context, urn resource.URN, inputs resource.PropertyMap,
+	deprecations []deprecation) error {
+
+	for _, d := range deprecations {
+		if !d.applies(inputs) {
+			continue
+		}
+		if err := p.warn(ctx, urn, d.message); err != nil {
+			return err
+		}
+		if d.upgrade != nil {
+			if err := d.upgrade(inputs); err != nil {
+				glog.V(9).Infof("failed to upgrade deprecated inputs for %v: %v", urn, err)
+			}
+		}
+	}
+	return nil
+}
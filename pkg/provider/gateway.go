@@ -0,0 +1,310 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"golang.org/x/net/http2"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failures to connect to a gateway after which requests to the
+	// gateway fail fast.
+	breakerThreshold = 5
+	// breakerCooldown is the time for which requests to an unreachable gateway fail fast before the gateway is tried
+	// again.
+	breakerCooldown = 30 * time.Second
+)
+
+// readPEM returns the PEM-encoded data held by the given value. The value may either be the PEM-encoded data itself
+// or the path to a file that contains it.
+func readPEM(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return ioutil.ReadFile(value)
+}
+
+// newTLSConfig creates the TLS configuration used to communicate with the given gateway.
+func newTLSConfig(g gateway) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: g.TLSSkipVerify}
+
+	if g.CACert != "" {
+		pem, err := readPEM(g.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "caCert")
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("caCert: no PEM-encoded certificates found")
+		}
+		config.RootCAs = roots
+	}
+
+	if g.ClientCert != "" || g.ClientKey != "" {
+		if g.ClientCert == "" || g.ClientKey == "" {
+			return nil, errors.New("clientCert and clientKey must be specified together")
+		}
+		certPEM, err := readPEM(g.ClientCert)
+		if err != nil {
+			return nil, errors.Wrap(err, "clientCert")
+		}
+		keyPEM, err := readPEM(g.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "clientKey")
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, errors.Wrap(err, "clientCert")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// clientOptions controls the behavior of the clients used to communicate with gateways, regardless of the gateway.
+type clientOptions struct {
+	// connectTimeout bounds the time spent establishing a connection, including the TLS handshake. Zero imposes no
+	// limit.
+	connectTimeout time.Duration
+	// requestTimeout bounds the time spent on a single request, including reading the response body. Zero imposes no
+	// limit.
+	requestTimeout time.Duration
+	// userAgent is the value of the User-Agent header sent with each request.
+	userAgent string
+
+	// maxIdleConns, maxIdleConnsPerHost, and idleConnTimeout control connection reuse. See http.Transport for
+	// details.
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	// disableHTTP2 disables HTTP/2 for TLS connections.
+	disableHTTP2 bool
+	// retryPolicy controls how idempotent requests that fail due to a transient error are retried.
+	retryPolicy client.RetryPolicy
+
+	// refreshCredentials causes credentials from dynamic sources to be re-resolved before each request.
+	refreshCredentials bool
+	// offline defers resolving credentials from dynamic sources until a request is made, as a credential helper may
+	// itself need network access.
+	offline bool
+
+	// rateLimiter limits the rate of requests to all gateways. A nil limiter means that the rate is unlimited.
+	rateLimiter *client.RateLimiter
+	// logger is notified of each request to a gateway, if non-nil.
+	logger client.Logger
+}
+
+// userAgent returns the User-Agent header value for the given provider version and optional user-supplied suffix.
+func userAgent(version, suffix string) string {
+	ua := "pulumi-openfaas/" + version
+	if suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
+
+// newHTTPClient creates the HTTP client used to communicate with the given gateway.
+func newHTTPClient(g gateway, opts clientOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(g)
+	if err != nil {
+		return nil, err
+	}
+	// Unless a proxy is configured explicitly, honor the standard HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+	// variables.
+	proxy := http.ProxyFromEnvironment
+	if g.ProxyURL != "" {
+		proxyURL, err := url.Parse(g.ProxyURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "proxyUrl")
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, errors.Errorf("proxyUrl: %q is not an absolute URL", g.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: opts.connectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   opts.connectTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          opts.maxIdleConns,
+		MaxIdleConnsPerHost:   opts.maxIdleConnsPerHost,
+		IdleConnTimeout:       opts.idleConnTimeout,
+	}
+	// A transport with a custom dialer or TLS configuration does not negotiate HTTP/2 unless it is configured to do
+	// so, and a non-nil, empty TLSNextProto map prevents it from ever doing so.
+	if opts.disableHTTP2 {
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else if err := http2.ConfigureTransport(transport); err != nil {
+		return nil, errors.Wrap(err, "http2")
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// logRequest reports a gateway request as a debug diagnostic so that gateway traffic can be inspected by running the
+// engine at high verbosity.
+func (p *faasProvider) logRequest(entry client.RequestLog) {
+	msg := fmt.Sprintf("gateway request %v %v", entry.Method, entry.Path)
+	if entry.Err != nil {
+		msg += fmt.Sprintf(" failed after %v: %v", entry.Duration, entry.Err)
+	} else {
+		msg += fmt.Sprintf(" returned %d in %v", entry.StatusCode, entry.Duration)
+	}
+
+	glog.V(9).Infof("%s: %s", p.label(), msg)
+	if p.host != nil {
+		contract.IgnoreError(p.host.Log(p.canceler.context, diag.Debug, "", msg))
+	}
+}
+
+// key returns a string that uniquely identifies the given gateway configuration.
+func (g gateway) key() string {
+	b, err := json.Marshal(g)
+	contract.AssertNoError(err)
+	return string(b)
+}
+
+// iamCredentials returns the OpenFaaS IAM credentials for the given gateway, if any.
+func (g gateway) iamCredentials() *client.IAMCredentials {
+	if g.OIDCIssuer == "" && g.OIDCClientID == "" && g.OIDCClientSecret == "" && g.OIDCToken == "" {
+		return nil
+	}
+	return &client.IAMCredentials{
+		Issuer:       g.OIDCIssuer,
+		ClientID:     g.OIDCClientID,
+		ClientSecret: g.OIDCClientSecret,
+		IDToken:      g.OIDCToken,
+	}
+}
+
+// gatewayAuthorization returns the Authorization header value used to authenticate with the given gateway, if any.
+// Gateways that use OpenFaaS IAM are authenticated by their transport instead.
+func gatewayAuthorization(g gateway) (string, error) {
+	iam := g.iamCredentials() != nil
+	switch {
+	case g.Token != "" && g.Username != "":
+		return "", errors.New("token and username cannot both be specified")
+	case iam && (g.Token != "" || g.Username != ""):
+		return "", errors.New("OIDC credentials cannot be combined with token or username")
+	case g.Token != "":
+		return client.BearerAuth(g.Token), nil
+	case g.Username != "":
+		return client.BasicAuth(g.Username, g.Password), nil
+	default:
+		return "", nil
+	}
+}
+
+// newFunctionsClient creates a client for the given gateway. It is the provider's default client constructor.
+func newFunctionsClient(ctx context.Context, g gateway, opts clientOptions) (client.FunctionsAPI, error) {
+	c, err := newGatewayClient(ctx, g, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newGatewayClient creates a client for the given gateway.
+func newGatewayClient(ctx context.Context, g gateway, opts clientOptions) (*client.Client, error) {
+	dynamic := g.envCredentials || g.CredentialCommand != ""
+	deferCredentials := opts.offline && dynamic && g.iamCredentials() == nil
+
+	// Offline providers do not resolve credentials from dynamic sources up front, as a credential helper may itself
+	// need network access. The credentials are resolved before each request instead.
+	resolved := g
+	if !deferCredentials {
+		var err error
+		if resolved, err = resolveCredentials(ctx, g); err != nil {
+			return nil, err
+		}
+	}
+	authorization, err := gatewayAuthorization(resolved)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(g, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{}
+	for k, v := range g.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+
+	var middleware []client.Middleware
+	if creds := g.iamCredentials(); creds != nil {
+		if err = creds.Validate(); err != nil {
+			return nil, err
+		}
+		middleware = append(middleware, func(next http.RoundTripper) http.RoundTripper {
+			return &client.IAMTransport{Base: next, GatewayURL: g.Endpoint, Credentials: *creds, Headers: headers}
+		})
+	} else if dynamic && (opts.refreshCredentials || deferCredentials) {
+		// Resolve the credentials before each request rather than once up front. OpenFaaS IAM tokens are refreshed
+		// by their transport regardless.
+		middleware = append(middleware, func(next http.RoundTripper) http.RoundTripper {
+			return &credentialTransport{base: next, gateway: g}
+		})
+		authorization = ""
+	}
+
+	// Identify the provider to the gateway so that operators can attribute API traffic. User-supplied headers take
+	// precedence.
+	options := []client.Option{
+		client.WithUserAgent(opts.userAgent),
+		// Retry outside of authentication so that each attempt is authenticated afresh, and outside of the circuit
+		// breaker so that retries stop once it opens.
+		client.WithRetries(opts.retryPolicy),
+		client.WithMiddleware(middleware...),
+		client.WithDefaultTimeout(opts.requestTimeout),
+		client.WithTracer(gatewayTracer{}),
+	}
+	if opts.logger != nil {
+		options = append(options, client.WithLogger(opts.logger))
+	}
+	// Fail fast once the gateway is evidently down rather than waiting for each pending operation to time out.
+	options = append(options, client.WithCircuitBreaker(client.NewCircuitBreaker(breakerThreshold, breakerCooldown)))
+	if opts.rateLimiter != nil {
+		// Throttle before authenticating so that token requests are not issued for requests that will wait.
+		options = append([]client.Option{client.WithRateLimiter(opts.rateLimiter)}, options...)
+	}
+	return client.NewClient(httpClient, g.Endpoint, authorization, headers, options...), nil
+}
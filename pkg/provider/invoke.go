@@ -0,0 +1,370 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+)
+
+const (
+	getGatewayHealthToken = "openfaas:index:getGatewayHealth"
+	getFunctionToken      = "openfaas:index:getFunction"
+	listFunctionsToken    = "openfaas:index:listFunctions"
+)
+
+// gatewayArgs selects the gateway that an invoke targets. If neither field is set, the provider's configured gateway
+// is used.
+type gatewayArgs struct {
+	Gateway        *gateway `pulumi:"gateway,optional"`
+	GatewayProfile string   `pulumi:"gatewayProfile,optional,conflictsWith=gateway"`
+}
+
+// gatewayHealth is the result of the getGatewayHealth invoke.
+type gatewayHealth struct {
+	Healthy bool   `pulumi:"healthy"`
+	Error   string `pulumi:"error,optional"`
+}
+
+// gatewayArgsDescriptions documents the arguments of invokes that target a gateway in the provider's schema.
+var gatewayArgsDescriptions = map[string]string{
+	"gateway": "The OpenFaaS gateway to target in place of the provider's configured gateway.",
+	"gatewayProfile": "The name of the provider's gateway profile to target in place of the provider's configured " +
+		"gateway. Cannot be combined with gateway.",
+}
+
+// gatewayHealthDescriptions documents the result of the getGatewayHealth invoke in the provider's schema.
+var gatewayHealthDescriptions = map[string]string{
+	"healthy": "Whether the gateway is reachable and healthy.",
+	"error":   "The reason that the gateway is unhealthy, if it is.",
+}
+
+// getFunctionArgs selects the function that the getFunction invoke fetches.
+type getFunctionArgs struct {
+	gatewayArgs
+	Service   string `pulumi:"service"`
+	Namespace string `pulumi:"namespace,optional"`
+}
+
+// getFunctionArgsDescriptions documents the arguments of the getFunction invoke in the provider's schema.
+var getFunctionArgsDescriptions = map[string]string{
+	"service":        "The name of the function to fetch.",
+	"namespace":      "The namespace of the function to fetch. Defaults to the provider's configured namespace.",
+	"gateway":        gatewayArgsDescriptions["gateway"],
+	"gatewayProfile": gatewayArgsDescriptions["gatewayProfile"],
+}
+
+// functionInfo is the result of the getFunction invoke: the specification and status of a function as reported by
+// the gateway.
+type functionInfo struct {
+	Service           string            `pulumi:"service"`
+	Namespace         string            `pulumi:"namespace,optional"`
+	Image             string            `pulumi:"image"`
+	Network           string            `pulumi:"network,optional"`
+	EnvProcess        string            `pulumi:"envProcess,optional"`
+	EnvVars           map[string]string `pulumi:"envVars,optional"`
+	Labels            map[string]string `pulumi:"labels,optional"`
+	Annotations       map[string]string `pulumi:"annotations,optional"`
+	Secrets           []string          `pulumi:"secrets,optional"`
+	Replicas          uint64            `pulumi:"replicas"`
+	AvailableReplicas uint64            `pulumi:"availableReplicas"`
+	InvocationCount   float64           `pulumi:"invocationCount"`
+	CreatedAt         string            `pulumi:"createdAt,optional"`
+	URL               string            `pulumi:"url"`
+}
+
+// functionInfoDescriptions documents the result of the getFunction invoke in the provider's schema.
+var functionInfoDescriptions = map[string]string{
+	"service":           "The name of the function.",
+	"namespace":         "The namespace of the function, if the gateway reports one.",
+	"image":             "The container image that implements the function.",
+	"network":           "The network that the function is attached to.",
+	"envProcess":        "The process that the function's watchdog runs to handle each request.",
+	"envVars":           "The environment variables set in the function's container.",
+	"labels":            "The labels applied to the function.",
+	"annotations":       "The annotations applied to the function.",
+	"secrets":           "The names of the secrets mounted into the function's container.",
+	"replicas":          "The number of replicas of the function that the gateway is trying to run.",
+	"availableReplicas": "The number of replicas of the function that are ready to serve requests.",
+	"invocationCount":   "The number of times the function has been invoked.",
+	"createdAt":         "The time at which the function was created, as an RFC 3339 timestamp, if reported.",
+	"url":               "The URL at which the function is invoked through the gateway.",
+}
+
+// listFunctionsArgs filters the functions that the listFunctions invoke returns.
+type listFunctionsArgs struct {
+	gatewayArgs
+	Namespace string            `pulumi:"namespace,optional"`
+	Labels    map[string]string `pulumi:"labels,optional"`
+}
+
+// listFunctionsArgsDescriptions documents the arguments of the listFunctions invoke in the provider's schema.
+var listFunctionsArgsDescriptions = map[string]string{
+	"namespace":      "The namespace to list functions in. Defaults to the provider's configured namespace.",
+	"labels":         "Labels that the listed functions must carry, with the given values.",
+	"gateway":        gatewayArgsDescriptions["gateway"],
+	"gatewayProfile": gatewayArgsDescriptions["gatewayProfile"],
+}
+
+// functionList is the result of the listFunctions invoke.
+type functionList struct {
+	Functions []functionInfo `pulumi:"functions"`
+}
+
+// functionListDescriptions documents the result of the listFunctions invoke in the provider's schema.
+var functionListDescriptions = map[string]string{
+	"functions": "The matching functions, ordered by name.",
+}
+
+// makeFunctionInfo describes the given function, which was listed or fetched from the gateway at the given endpoint.
+// Functions that the gateway reports without a namespace are in the given namespace.
+func makeFunctionInfo(f *client.Function, endpoint, namespace string) functionInfo {
+	if f.Namespace != "" {
+		namespace = f.Namespace
+	}
+	live := makeFunction(f)
+	return functionInfo{
+		Service:           live.Service,
+		Namespace:         namespace,
+		Image:             live.Image,
+		Network:           live.Network,
+		EnvProcess:        live.EnvProcess,
+		EnvVars:           live.EnvVars,
+		Labels:            live.Labels,
+		Annotations:       live.Annotations,
+		Secrets:           live.Secrets,
+		Replicas:          live.Replicas,
+		AvailableReplicas: live.AvailableReplicas,
+		InvocationCount:   live.InvocationCount,
+		CreatedAt:         live.CreatedAt,
+		URL:               functionURL(endpoint, live.Service, namespace),
+	}
+}
+
+// endpointFor returns the endpoint of the given gateway, or of the configured gateway if the given gateway is nil.
+func (p *faasProvider) endpointFor(g *gateway) string {
+	if g == nil {
+		return p.endpoint
+	}
+	return g.Endpoint
+}
+
+// functionURL returns the URL at which the function with the given service name and namespace is invoked through
+// the gateway at the given endpoint.
+func functionURL(endpoint, service, namespace string) string {
+	u := strings.TrimSuffix(endpoint, "/") + "/function/" + service
+	if namespace != "" {
+		u += "." + namespace
+	}
+	return u
+}
+
+// invokeFunc implements a built-in function. Failures describe invalid arguments.
+type invokeFunc func(p *faasProvider, ctx context.Context,
+	args resource.PropertyMap) (resource.PropertyMap, []*pulumirpc.CheckFailure, error)
+
+// invokes maps the tokens of the provider's built-in functions to their implementations.
+var invokes = map[string]invokeFunc{
+	getGatewayHealthToken: (*faasProvider).getGatewayHealth,
+	getFunctionToken:      (*faasProvider).getFunction,
+	listFunctionsToken:    (*faasProvider).listFunctions,
+}
+
+// invokeSchema describes the arguments and result of a built-in function.
+type invokeSchema struct {
+	args   interface{}
+	result interface{}
+}
+
+// invokeSchemas maps the tokens of the provider's built-in functions to their schemas.
+var invokeSchemas = map[string]invokeSchema{
+	getGatewayHealthToken: {args: gatewayArgs{}, result: gatewayHealth{}},
+	getFunctionToken:      {args: getFunctionArgs{}, result: functionInfo{}},
+	listFunctionsToken:    {args: listFunctionsArgs{}, result: functionList{}},
+}
+
+// Invoke dynamically executes a built-in function in the provider.
+func (p *faasProvider) Invoke(ctx context.Context, req *pulumirpc.InvokeRequest) (*pulumirpc.InvokeResponse, error) {
+	label := fmt.Sprintf("%s.Invoke(%s)", p.label(), req.GetTok())
+	glog.V(9).Infof("%s executing", label)
+
+	invoke, ok := invokes[req.GetTok()]
+	if !ok {
+		return nil, errors.Errorf("unknown Invoke token %v", req.GetTok())
+	}
+
+	args, err := plugin.UnmarshalProperties(req.GetArgs(), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.args", label), SkipNulls: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, failures, err := invoke(p, ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) != 0 {
+		return &pulumirpc.InvokeResponse{Failures: failures}, nil
+	}
+
+	ret, err := plugin.MarshalProperties(result, plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.return", label), SkipNulls: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pulumirpc.InvokeResponse{Return: ret}, nil
+}
+
+// getGatewayHealth checks the health of a gateway. An unhealthy gateway is reported in the result rather than as an
+// error so that programs can react to it.
+func (p *faasProvider) getGatewayHealth(ctx context.Context,
+	args resource.PropertyMap) (resource.PropertyMap, []*pulumirpc.CheckFailure, error) {
+
+	failures, err := checkProperties(args, gatewayArgs{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if failures = append(failures, p.checkGatewayProfile(args)...); len(failures) != 0 {
+		return nil, failures, nil
+	}
+	if p.offline {
+		return nil, nil, errOffline
+	}
+
+	g, err := p.gatewayFromProperties(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	health := gatewayHealth{Healthy: true}
+	if err = c.Healthz(ctx); err != nil {
+		health = gatewayHealth{Error: err.Error()}
+	}
+	result, err := encodeProperties(health)
+	return result, nil, err
+}
+
+// getFunction fetches the specification and status of a function, which need not be managed by the program.
+func (p *faasProvider) getFunction(ctx context.Context,
+	args resource.PropertyMap) (resource.PropertyMap, []*pulumirpc.CheckFailure, error) {
+
+	failures, err := checkProperties(args, getFunctionArgs{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if failures = append(failures, p.checkGatewayProfile(args)...); len(failures) != 0 {
+		return nil, failures, nil
+	}
+	if p.offline {
+		return nil, nil, errOffline
+	}
+
+	var a getFunctionArgs
+	if err = decodeProperties(args, &a); err != nil {
+		return nil, nil, err
+	}
+	if a.Namespace == "" {
+		a.Namespace = p.namespace
+	}
+	g, err := p.gatewayFromProperties(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var f *client.Function
+	err = p.gatewayCall(ctx, func() (err error) {
+		f, err = c.GetFunction(ctx, a.Service, a.Namespace)
+		return err
+	})
+	if err == client.ErrNotFound {
+		return nil, nil, errors.Errorf("function %v not found", functionID(a.Service, a.Namespace))
+	}
+	if err != nil {
+		return nil, nil, gatewayError(err)
+	}
+
+	result, err := encodeProperties(makeFunctionInfo(f, p.endpointFor(g), a.Namespace))
+	return result, nil, err
+}
+
+// listFunctions lists the functions in a namespace, optionally filtered by their labels. The functions need not be
+// managed by the program.
+func (p *faasProvider) listFunctions(ctx context.Context,
+	args resource.PropertyMap) (resource.PropertyMap, []*pulumirpc.CheckFailure, error) {
+
+	failures, err := checkProperties(args, listFunctionsArgs{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if failures = append(failures, p.checkGatewayProfile(args)...); len(failures) != 0 {
+		return nil, failures, nil
+	}
+	if p.offline {
+		return nil, nil, errOffline
+	}
+
+	var a listFunctionsArgs
+	if err = decodeProperties(args, &a); err != nil {
+		return nil, nil, err
+	}
+	if a.Namespace == "" {
+		a.Namespace = p.namespace
+	}
+	g, err := p.gatewayFromProperties(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var functions []client.Function
+	err = p.gatewayCall(ctx, func() (err error) {
+		functions, err = c.ListFunctionsWithLabels(ctx, a.Namespace, a.Labels)
+		return err
+	})
+	if err != nil {
+		return nil, nil, gatewayError(err)
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Service < functions[j].Service })
+
+	list := functionList{Functions: make([]functionInfo, len(functions))}
+	for i := range functions {
+		list.Functions[i] = makeFunctionInfo(&functions[i], p.endpointFor(g), a.Namespace)
+	}
+	result, err := encodeProperties(list)
+	return result, nil, err
+}
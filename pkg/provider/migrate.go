@@ -0,0 +1,99 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// stateVersionKey is the output property that records the version of the schema that a resource's state was written
+// with. State without this property was written before state was versioned and is treated as version 0.
+const stateVersionKey = resource.PropertyKey("__schemaVersion")
+
+// stateMigrations holds the functions that upgrade resource state between schema versions: stateMigrations[i]
+// upgrades state from version i to version i+1. Append a migration here whenever the schema changes in a way that
+// existing state cannot be decoded or diffed as-is.
+var stateMigrations = []func(state resource.PropertyMap) error{
+	migrateListLabels,
+}
+
+// currentStateVersion is the version of the schema that the provider writes.
+var currentStateVersion = len(stateMigrations)
+
+// migrateState upgrades the given resource state to the current schema version. The state is modified in place.
+func migrateState(state resource.PropertyMap) error {
+	version := 0
+	if v, ok := state[stateVersionKey]; ok {
+		if !v.IsNumber() {
+			return errors.Errorf("invalid state schema version %v", v)
+		}
+		version = int(v.NumberValue())
+	}
+	if version > currentStateVersion {
+		return errors.Errorf("resource state has schema version %v, but this provider only understands versions up "+
+			"to %v; please upgrade the OpenFaaS provider", version, currentStateVersion)
+	}
+
+	for ; version < currentStateVersion; version++ {
+		if err := stateMigrations[version](state); err != nil {
+			return errors.Wrapf(err, "migrating resource state from schema version %v", version)
+		}
+	}
+	state[stateVersionKey] = resource.NewNumberProperty(float64(currentStateVersion))
+	return nil
+}
+
+// versionedState returns a copy of the given resource state that records the current schema version.
+func versionedState(state resource.PropertyMap) resource.PropertyMap {
+	versioned := state.Copy()
+	versioned[stateVersionKey] = resource.NewNumberProperty(float64(currentStateVersion))
+	return versioned
+}
+
+// migrateListLabels upgrades labels and annotations that were recorded as lists of "key=value" strings to maps.
+func migrateListLabels(state resource.PropertyMap) error {
+	for _, k := range []resource.PropertyKey{"labels", "annotations"} {
+		if err := listToMap(state, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listToMap converts the property with the given key from a list of "key=value" strings to a map. Properties that
+// are not lists are left unchanged.
+func listToMap(props resource.PropertyMap, key resource.PropertyKey) error {
+	v, ok := props[key]
+	if !ok || !v.IsArray() {
+		return nil
+	}
+
+	m := resource.PropertyMap{}
+	for _, e := range v.ArrayValue() {
+		if !e.IsString() {
+			return errors.Errorf("%v: expected a string value, received a %v", key, e.TypeString())
+		}
+		kv := strings.SplitN(e.StringValue(), "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("%v: expected a key=value string, received %q", key, e.StringValue())
+		}
+		m[resource.PropertyKey(kv[0])] = resource.NewStringProperty(kv[1])
+	}
+	props[key] = resource.NewObjectProperty(m)
+	return nil
+}
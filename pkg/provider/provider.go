@@ -16,16 +16,24 @@ package provider
 
 import (
 	"context"
-	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
-	"strconv"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	pbempty "github.com/golang/protobuf/ptypes/empty"
+	pbstruct "github.com/golang/protobuf/ptypes/struct"
 	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/pkg/diag"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/resource/provider"
+	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/rpcutil/rpcerror"
 	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
 	"google.golang.org/grpc/codes"
@@ -33,9 +41,20 @@ import (
 	"github.com/pulumi/pulumi-openfaas/pkg/client"
 )
 
+// cancelDrainTimeout is the amount of time in-flight operations are given to complete once the provider has been
+// canceled before they are aborted.
+const cancelDrainTimeout = 5 * time.Second
+
+// errCanceled is returned for operations that begin after the provider has been canceled.
+var errCanceled = errors.New("the OpenFaaS provider is shutting down")
+
 type cancellationContext struct {
 	context context.Context
 	cancel  context.CancelFunc
+
+	lock     sync.Mutex
+	canceled bool
+	inflight sync.WaitGroup
 }
 
 func makeCancellationContext() *cancellationContext {
@@ -46,18 +65,77 @@ func makeCancellationContext() *cancellationContext {
 	}
 }
 
+// begin registers the start of an operation. The returned function must be called once the operation completes. If
+// the provider has been canceled, begin returns errCanceled and the operation must not proceed.
+func (c *cancellationContext) begin() (func(), error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.canceled {
+		return nil, errCanceled
+	}
+	c.inflight.Add(1)
+	return c.inflight.Done, nil
+}
+
+// drain stops accepting new operations, gives in-flight operations up to the given timeout to complete, and then
+// cancels any operations that are still running.
+func (c *cancellationContext) drain(timeout time.Duration) {
+	c.lock.Lock()
+	c.canceled = true
+	c.lock.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+	}
+	c.cancel()
+}
+
 type faasProvider struct {
+	host     *provider.HostClient
 	canceler *cancellationContext
-	client   *client.Client
+	client   client.FunctionsAPI
+	endpoint string // the endpoint of the configured gateway
 	name     string
 	version  string
+
+	namespace          string
+	defaultLabels      map[string]string
+	defaultAnnotations map[string]string
+	// lenientPropertyKeys causes Check to accept property names that differ from the schema's only in case and
+	// separators.
+	lenientPropertyKeys bool
+	// coerceStrings causes Check to convert strings to booleans and numbers where the schema expects them.
+	coerceStrings bool
+
+	gatewayProfiles         map[string]gateway
+	offline                 bool
+	clientOptions           clientOptions
+	defaultOperationTimeout time.Duration
+
+	gatewayClientsLock sync.Mutex
+	gatewayClients     map[string]client.FunctionsAPI
+	// newClient creates the client for a gateway. It may be replaced, e.g. by tests, to substitute another client.
+	newClient func(ctx context.Context, g gateway, opts clientOptions) (client.FunctionsAPI, error)
+
+	// gatewaySlots bounds the number of concurrent gateway calls. A nil channel means that calls are unbounded.
+	gatewaySlots chan struct{}
 }
 
-func makeFaasProvider(name, version string) (pulumirpc.ResourceProviderServer, error) {
+func makeFaasProvider(host *provider.HostClient, name, version string) (pulumirpc.ResourceProviderServer, error) {
 	return &faasProvider{
-		canceler: makeCancellationContext(),
-		name:     name,
-		version:  version,
+		host:      host,
+		canceler:  makeCancellationContext(),
+		name:      name,
+		version:   version,
+		newClient: newFunctionsClient,
 	}, nil
 }
 
@@ -65,60 +143,757 @@ func (p *faasProvider) label() string {
 	return fmt.Sprintf("Provider[%s]", p.name)
 }
 
+// warn reports a warning about the given resource to the user. Providers that are not connected to an engine, e.g. in
+// tests, log the warning instead.
+func (p *faasProvider) warn(ctx context.Context, urn resource.URN, msg string) error {
+	if p.host == nil {
+		glog.V(9).Infof("%s: warning for %v: %s", p.label(), urn, msg)
+		return nil
+	}
+	return p.host.Log(ctx, diag.Warning, urn, msg)
+}
+
+// operationTimeout returns the timeout for a resource operation given the engine-provided timeout in seconds. A
+// timeout of zero indicates that the user did not specify a custom timeout for the operation, in which case the
+// provider's configured operation timeout, if any, applies.
+func (p *faasProvider) operationTimeout(timeout float64) time.Duration {
+	if timeout == 0 {
+		return p.defaultOperationTimeout
+	}
+	return seconds(timeout)
+}
+
+// operationContext returns a context for a resource operation with the given timeout. A timeout of zero indicates
+// that the operation is not time-limited. The context is canceled when the provider is canceled rather than when the
+// RPC context ctx is, but carries ctx's tracing span so that gateway requests are traced as part of the operation.
+func (p *faasProvider) operationContext(ctx context.Context,
+	timeout time.Duration) (context.Context, context.CancelFunc) {
+
+	opCtx := withSpan(p.canceler.context, ctx)
+	if timeout == 0 {
+		return context.WithCancel(opCtx)
+	}
+	return context.WithTimeout(opCtx, timeout)
+}
+
+// timeoutError annotates an error that was caused by an operation exceeding its timeout.
+func timeoutError(ctx context.Context, err error, op string, timeout time.Duration) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Wrapf(err, "%s did not complete within the %v timeout", op, timeout)
+	}
+	return err
+}
+
+// errOffline is returned for operations that require the gateway when the provider is configured for offline use.
+var errOffline = rpcerror.New(codes.FailedPrecondition, "the OpenFaaS provider is configured for offline use and "+
+	"cannot contact the gateway; set openfaas:config:offline to false to deploy")
+
 // Configure configures the resource provider with "globals" that control its behavior.
 func (p *faasProvider) Configure(_ context.Context, req *pulumirpc.ConfigureRequest) (*pbempty.Empty, error) {
-	const faasNamespace = "openfaas:config:"
+	glog.V(9).Infof("%s.Configure(%v)", p.label(), redactConfig(req.GetVariables()))
+
+	cfg, err := decodeConfig(req.GetVariables())
+	if err != nil {
+		return nil, err
+	}
 
-	vars := req.GetVariables()
+	p.namespace = cfg.Namespace
+	p.offline = cfg.Offline
+	p.lenientPropertyKeys = cfg.LenientPropertyKeys
+	p.coerceStrings = cfg.CoerceStrings
+	p.defaultLabels, p.defaultAnnotations = cfg.DefaultLabels, cfg.DefaultAnnotations
+	if cfg.Parallelism > 0 {
+		p.gatewaySlots = make(chan struct{}, cfg.Parallelism)
+	}
+
+	p.clientOptions = clientOptions{
+		connectTimeout: seconds(cfg.ConnectTimeout),
+		requestTimeout: seconds(cfg.RequestTimeout),
+		userAgent:      userAgent(p.version, cfg.UserAgentSuffix),
+
+		maxIdleConns:        defaultMaxIdleConns,
+		maxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		idleConnTimeout:     defaultIdleConnTimeout,
+		disableHTTP2:        cfg.HTTP2 != nil && !*cfg.HTTP2,
+
+		retryPolicy: client.DefaultRetryPolicy,
 
-	endpoint, ok := vars[faasNamespace+"endpoint"]
-	if !ok {
-		missingKey := &pulumirpc.ConfigureErrorMissingKeys_MissingKey{
-			Name:        "openfaas:config:endpoint",
-			Description: "the endpoint of the OpenFaaS API gateway",
+		refreshCredentials: cfg.RefreshCredentials,
+		offline:            cfg.Offline,
+		logger:             client.LoggerFunc(p.logRequest),
+	}
+	if cfg.MaxRetries != nil {
+		p.clientOptions.retryPolicy.MaxRetries = *cfg.MaxRetries
+	}
+	if cfg.MaxIdleConns != nil {
+		p.clientOptions.maxIdleConns = *cfg.MaxIdleConns
+	}
+	if cfg.RequestsPerSecond > 0 {
+		p.clientOptions.rateLimiter = client.NewRateLimiter(cfg.RequestsPerSecond, cfg.Burst)
+	}
+	if cfg.IdleConnTimeout != nil {
+		p.clientOptions.idleConnTimeout = seconds(*cfg.IdleConnTimeout)
+	}
+	p.defaultOperationTimeout = seconds(cfg.OperationTimeout)
+
+	p.endpoint = cfg.Endpoint
+	p.client, err = p.newClient(p.canceler.context, gateway{
+		Endpoint:          cfg.Endpoint,
+		Username:          cfg.Username,
+		Password:          cfg.Password,
+		Token:             cfg.Token,
+		OIDCIssuer:        cfg.OIDCIssuer,
+		OIDCClientID:      cfg.OIDCClientID,
+		OIDCClientSecret:  cfg.OIDCClientSecret,
+		OIDCToken:         cfg.OIDCToken,
+		TLSSkipVerify:     cfg.TLSSkipVerify,
+		CACert:            cfg.CACert,
+		ClientCert:        cfg.ClientCert,
+		ClientKey:         cfg.ClientKey,
+		ProxyURL:          cfg.ProxyURL,
+		Headers:           cfg.Headers,
+		CredentialCommand: cfg.CredentialCommand,
+		CredentialArgs:    cfg.CredentialArgs,
+		envCredentials:    credentialsFromEnv(req.GetVariables()),
+	}, p.clientOptions)
+	if err != nil {
+		return nil, rpcerror.Newf(codes.InvalidArgument, "invalid configuration: %v%v", faasConfigNamespace, err)
+	}
+
+	// Create the clients for the gateway profiles up front so that any misconfiguration is reported immediately.
+	p.gatewayProfiles = cfg.Gateways
+	for name, g := range cfg.Gateways {
+		g := g
+		if _, err = p.clientFor(&g); err != nil {
+			return nil, rpcerror.Newf(codes.InvalidArgument, "invalid configuration: %vgateways.%v: %v",
+				faasConfigNamespace, name, err)
 		}
+	}
 
-		err := rpcerror.New(codes.InvalidArgument, "required configuration keys were missing")
+	// Unless disabled, make sure that the gateway is reachable so that misconfiguration is reported up front rather
+	// than as a confusing failure during the first resource operation.
+	if !cfg.SkipHealthCheck && !cfg.Offline {
+		if err := p.client.Healthz(p.canceler.context); err != nil {
+			return nil, gatewayUnreachableError(cfg.Endpoint, err)
+		}
+	}
 
-		// Clients of our RPC endpoint will be looking for this detail in order to figure out
-		// which keys need descriptive error messages.
-		return nil, rpcerror.WithDetails(err, &pulumirpc.ConfigureErrorMissingKeys{
-			MissingKeys: []*pulumirpc.ConfigureErrorMissingKeys_MissingKey{missingKey},
-		})
+	return &pbempty.Empty{}, nil
+}
+
+// gatewayUnreachableError creates the error reported when the gateway at the given endpoint fails its health check.
+func gatewayUnreachableError(endpoint string, err error) error {
+	hint := "check that openfaas:config:endpoint is correct and that the gateway is running"
+	cause := errors.Cause(err)
+	if urlErr, ok := cause.(*url.Error); ok {
+		cause = urlErr.Err
 	}
+	switch cause.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		hint = "the gateway's TLS certificate could not be verified; if the gateway uses a self-signed " +
+			"certificate, set openfaas:config:caCert to the certificate of its CA"
+	}
+	return rpcerror.Newf(errorCode(err), "cannot reach OpenFaaS gateway at %v (%v): %v. To skip this check, set "+
+		"openfaas:config:skipHealthCheck to true", endpoint, hint, err)
+}
 
-	username, password := vars[faasNamespace+"username"], vars[faasNamespace+"password"]
+// clientFor returns the client for the given gateway. If the gateway is nil, the client for the provider's configured
+// gateway is returned.
+func (p *faasProvider) clientFor(g *gateway) (client.FunctionsAPI, error) {
+	if g == nil {
+		return p.client, nil
+	}
 
-	tlsSkipVerify, _ := strconv.ParseBool(vars[faasNamespace+"tlsSkipVerify"])
+	p.gatewayClientsLock.Lock()
+	defer p.gatewayClientsLock.Unlock()
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: tlsSkipVerify},
+	key := g.key()
+	if c, ok := p.gatewayClients[key]; ok {
+		return c, nil
+	}
+	if p.gatewayClients == nil {
+		p.gatewayClients = map[string]client.FunctionsAPI{}
 	}
-	httpClient := &http.Client{Transport: tr}
+	c, err := p.newClient(p.canceler.context, *g, p.clientOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway")
+	}
+	p.gatewayClients[key] = c
+	return c, nil
+}
 
-	p.client = client.NewClient(httpClient, endpoint, username, password)
+// gateway describes an OpenFaaS gateway that a resource uses in place of the provider's configured gateway.
+type gateway struct {
+	Endpoint          string            `pulumi:"endpoint,forceNew"`
+	Username          string            `pulumi:"username,optional"`
+	Password          string            `pulumi:"password,optional,secret"`
+	Token             string            `pulumi:"token,optional,secret"`
+	OIDCIssuer        string            `pulumi:"oidcIssuer,optional"`
+	OIDCClientID      string            `pulumi:"oidcClientId,optional"`
+	OIDCClientSecret  string            `pulumi:"oidcClientSecret,optional,secret"`
+	OIDCToken         string            `pulumi:"oidcToken,optional,secret"`
+	TLSSkipVerify     bool              `pulumi:"tlsSkipVerify,optional"`
+	CACert            string            `pulumi:"caCert,optional"`
+	ClientCert        string            `pulumi:"clientCert,optional"`
+	ClientKey         string            `pulumi:"clientKey,optional,secret"`
+	ProxyURL          string            `pulumi:"proxyUrl,optional"`
+	Headers           map[string]string `pulumi:"headers,optional,secret"`
+	CredentialCommand string            `pulumi:"credentialCommand,optional"`
+	CredentialArgs    []string          `pulumi:"credentialArgs,optional"`
 
-	return &pbempty.Empty{}, nil
+	// envCredentials is true if the gateway's credentials were taken from environment variables.
+	envCredentials bool
 }
 
-// Invoke dynamically executes a built-in function in the provider.
-func (p *faasProvider) Invoke(context.Context, *pulumirpc.InvokeRequest) (*pulumirpc.InvokeResponse, error) {
-	panic("Invoke not implemented")
+// gatewayFromProperties returns the gateway, if any, that the resource with the given properties uses in place of the
+// provider's configured gateway. The gateway is either specified inline or selected by name from the provider's
+// gateway profiles.
+func (p *faasProvider) gatewayFromProperties(props resource.PropertyMap) (*gateway, error) {
+	if v := plainValue(props["gatewayProfile"]); v.IsString() {
+		g, ok := p.gatewayProfiles[v.StringValue()]
+		if !ok {
+			return nil, errors.Errorf("unknown gateway profile %q", v.StringValue())
+		}
+		return &g, nil
+	}
+
+	v := plainValue(props["gateway"])
+	if v.IsNull() {
+		return nil, nil
+	}
+	if !v.IsObject() {
+		return nil, failureError(typeMismatch("gateway", "object", v))
+	}
+
+	var g gateway
+	if err := decodeProperties(v.ObjectValue(), &g); err != nil {
+		return nil, errors.Wrap(err, "gateway")
+	}
+	return &g, nil
+}
+
+// gatewayEndpoint returns the endpoint of the gateway that the given properties target, which is the provider's
+// configured gateway unless they select another. It returns false if the gateway is not yet known.
+func (p *faasProvider) gatewayEndpoint(props resource.PropertyMap) (string, bool) {
+	if props["gateway"].ContainsUnknowns() || props["gatewayProfile"].ContainsUnknowns() {
+		return "", false
+	}
+	g, err := p.gatewayFromProperties(props)
+	switch {
+	case err != nil:
+		return "", false
+	case g == nil:
+		return strings.TrimSuffix(p.endpoint, "/"), true
+	default:
+		return strings.TrimSuffix(g.Endpoint, "/"), true
+	}
+}
+
+// checkGatewayProfile checks that the gateway profile, if any, selected by the given properties exists.
+func (p *faasProvider) checkGatewayProfile(props resource.PropertyMap) []*pulumirpc.CheckFailure {
+	v := plainValue(props["gatewayProfile"])
+	if !v.IsString() {
+		return nil
+	}
+
+	if _, ok := p.gatewayProfiles[v.StringValue()]; ok {
+		return nil
+	}
+	names := make([]string, 0, len(p.gatewayProfiles))
+	for name := range p.gatewayProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return []*pulumirpc.CheckFailure{{
+		Property: "gatewayProfile",
+		Reason: fmt.Sprintf("unknown gateway profile %q (configured profiles: [%v])", v.StringValue(),
+			strings.Join(names, ", ")),
+	}}
 }
 
 type function struct {
-	Service      string            `pulumi:"service,forceNew"`
-	Network      string            `pulumi:"network,optional"`
+	// Service must be a DNS label, as it names the function's Kubernetes deployment and service.
+	Service      string            `pulumi:"service,forceNew,pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"`
+	Namespace    string            `pulumi:"namespace,optional,forceNew"`
+	Network      string            `pulumi:"network,optional,computed"`
 	Image        string            `pulumi:"image"`
-	EnvProcess   string            `pulumi:"envProcess,optional"`
+	EnvProcess   string            `pulumi:"envProcess,optional,computed"`
 	EnvVars      map[string]string `pulumi:"envVars,optional"`
 	Labels       map[string]string `pulumi:"labels,optional"`
 	Annotations  map[string]string `pulumi:"annotations,optional"`
-	Secrets      []string          `pulumi:"secrets,optional"`
-	RegistryAuth string            `pulumi:"registryAuth,optional"`
+	Secrets      []string          `pulumi:"secrets,optional,set"`
+	RegistryAuth string            `pulumi:"registryAuth,optional,secret"`
+
+	// Gateway overrides the provider's configured gateway for this function.
+	Gateway *gateway `pulumi:"gateway,optional"`
+
+	// GatewayProfile selects one of the provider's gateway profiles in place of the provider's configured gateway.
+	GatewayProfile string `pulumi:"gatewayProfile,optional,forceNew,conflictsWith=gateway"`
+
+	// SkipAwait disables waiting for a newly-created function to become ready.
+	SkipAwait bool `pulumi:"skipAwait,optional"`
+
+	// DeleteBeforeReplace controls whether the function is deleted before its replacement is created. If unset, the
+	// function is deleted first only if its replacement has the same ID.
+	DeleteBeforeReplace *bool `pulumi:"deleteBeforeReplace,optional"`
+
+	// The remaining properties describe the function's status. They are reported by the gateway and cannot be set.
+
+	Replicas          uint64  `pulumi:"replicas,optional,computed"`
+	AvailableReplicas uint64  `pulumi:"availableReplicas,optional,computed"`
+	InvocationCount   float64 `pulumi:"invocationCount,optional,computed"`
+	CreatedAt         string  `pulumi:"createdAt,optional,computed"`
+
+	// CustomResource is the function rendered as an openfaas.com/v1 Function custom resource in YAML.
+	CustomResource string `pulumi:"customResource,optional,computed"`
+}
+
+// functionDescriptions documents the properties of a function in the provider's schema.
+var functionDescriptions = map[string]string{
+	"service": "The name of the function. This names the function's deployment and service, so it must be a " +
+		"DNS label.",
+	"namespace":    "The namespace to deploy the function to. Defaults to the provider's configured namespace.",
+	"network":      "The network that the function is attached to.",
+	"image":        "The container image that implements the function.",
+	"envProcess":   "The process that the function's watchdog runs to handle each request.",
+	"envVars":      "The environment variables to set in the function's container.",
+	"labels":       "The labels to apply to the function.",
+	"annotations":  "The annotations to apply to the function.",
+	"secrets":      "The names of the secrets to mount into the function's container.",
+	"registryAuth": "The credentials to use when pulling the function's image from a private registry.",
+	"gateway":      "The OpenFaaS gateway to deploy this function to. Overrides the provider's configured gateway.",
+	"gatewayProfile": "The name of the provider's gateway profile to deploy this function to. Overrides the " +
+		"provider's configured gateway. Cannot be combined with gateway.",
+	"skipAwait": "Whether to skip waiting for this function to become ready after it is created. When a function " +
+		"is replaced, the function it replaces is not deleted until the new function is ready unless this is set.",
+	"deleteBeforeReplace": "Whether to delete this function before creating its replacement. By default, the " +
+		"function is deleted first only if its replacement has the same service name and namespace.",
+	"replicas":          "The number of replicas of this function that the gateway is trying to run.",
+	"availableReplicas": "The number of replicas of this function that are ready to serve requests.",
+	"invocationCount":   "The number of times this function has been invoked.",
+	"createdAt": "The time at which this function was created, as an RFC 3339 timestamp, if reported by the " +
+		"gateway.",
+	"customResource": "This function rendered as an openfaas.com/v1 Function custom resource in YAML, for use " +
+		"with the OpenFaaS operator.",
+}
+
+const functionType = "openfaas:index:Function"
+
+const (
+	// readinessTimeout bounds the time spent waiting for a new function to become ready if the user has not
+	// specified a custom timeout.
+	readinessTimeout = 5 * time.Minute
+	// readinessPollInterval is the interval at which a new function's readiness is checked.
+	readinessPollInterval = 2 * time.Second
+)
+
+// functionTypeAliases lists the type tokens that functions were previously registered under. Resources of these types
+// are treated as functions so that existing stacks can be upgraded without replacing their functions.
+var functionTypeAliases = []tokens.Type{"openfaas:system:Function"}
+
+// isFunctionType returns true if the given type token refers to a function.
+func isFunctionType(t tokens.Type) bool {
+	if t == functionType {
+		return true
+	}
+	for _, alias := range functionTypeAliases {
+		if t == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// functionID returns the resource ID for the function with the given service name and namespace. Functions in the
+// gateway's default namespace are identified by their service name alone; functions in other namespaces use the
+// OpenFaaS "name.namespace" convention.
+func functionID(service, namespace string) string {
+	if namespace == "" {
+		return service
+	}
+	return service + "." + namespace
+}
+
+// parseFunctionID splits a function resource ID into its service name and namespace. Service names must be valid DNS
+// labels, so the first '.' (if any) always separates the name from the namespace.
+func parseFunctionID(id string) (service, namespace string) {
+	if i := strings.IndexByte(id, '.'); i != -1 {
+		return id[:i], id[i+1:]
+	}
+	return id, ""
+}
+
+func (f *function) clientFunction() *client.Function {
+	return &client.Function{
+		Service:      f.Service,
+		Namespace:    f.Namespace,
+		Network:      f.Network,
+		Image:        f.Image,
+		EnvProcess:   f.EnvProcess,
+		EnvVars:      f.EnvVars,
+		Labels:       f.Labels,
+		Annotations:  f.Annotations,
+		Secrets:      f.Secrets,
+		RegistryAuth: f.RegistryAuth,
+	}
+}
+
+func makeFunction(f *client.Function) function {
+	createdAt := ""
+	if f.CreatedAt != nil {
+		createdAt = f.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return function{
+		Service:           f.Service,
+		Namespace:         f.Namespace,
+		Network:           f.Network,
+		Image:             f.Image,
+		EnvProcess:        f.EnvProcess,
+		EnvVars:           f.EnvVars,
+		Labels:            f.Labels,
+		Annotations:       f.Annotations,
+		Secrets:           f.Secrets,
+		RegistryAuth:      f.RegistryAuth,
+		Replicas:          f.Replicas,
+		AvailableReplicas: f.AvailableReplicas,
+		InvocationCount:   f.InvocationCount,
+		CreatedAt:         createdAt,
+		CustomResource:    f.CustomResource(),
+	}
+}
+
+// specUnchanged returns true if updating the given live function to the given desired specification would not
+// change anything material. Fields that the desired specification leaves empty are left to the gateway, so they match
+// any live value. The registry credentials, which the gateway does not report, are not compared.
+func specUnchanged(live, desired *client.Function) bool {
+	sameString := func(live, desired string) bool {
+		return desired == "" || live == desired
+	}
+	sameMap := func(live, desired map[string]string) bool {
+		if len(live) != len(desired) {
+			return false
+		}
+		for k, v := range desired {
+			if lv, ok := live[k]; !ok || lv != v {
+				return false
+			}
+		}
+		return true
+	}
+	sameSet := func(live, desired []string) bool {
+		if len(live) != len(desired) {
+			return false
+		}
+		l, d := append([]string(nil), live...), append([]string(nil), desired...)
+		sort.Strings(l)
+		sort.Strings(d)
+		for i := range l {
+			if l[i] != d[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	return live.Image == desired.Image &&
+		sameString(live.Network, desired.Network) &&
+		sameString(live.EnvProcess, desired.EnvProcess) &&
+		sameMap(live.EnvVars, desired.EnvVars) &&
+		sameMap(live.Labels, desired.Labels) &&
+		sameMap(live.Annotations, desired.Annotations) &&
+		sameSet(live.Secrets, desired.Secrets)
+}
+
+// outputOnlyProperties lists the function properties that are reported by the gateway and cannot be set.
+var outputOnlyProperties = []resource.PropertyKey{
+	"replicas", "availableReplicas", "invocationCount", "createdAt", "customResource",
+}
+
+// inputOnlyProperties lists the function properties that the gateway does not report. Their values are carried over
+// from the recorded inputs when reading a function's live state.
+var inputOnlyProperties = []resource.PropertyKey{
+	"registryAuth", "gateway", "gatewayProfile", "skipAwait", "deleteBeforeReplace",
+}
+
+// liveProperties encodes the live state of a function. Empty values are omitted so that the properties match a
+// program that simply leaves the corresponding inputs unset.
+func liveProperties(f function) (resource.PropertyMap, error) {
+	props, err := encodeProperties(f)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range props {
+		switch v = plainValue(v); {
+		case v.IsNull(),
+			v.IsString() && v.StringValue() == "",
+			v.IsArray() && len(v.ArrayValue()) == 0,
+			v.IsObject() && len(v.ObjectValue()) == 0:
+			delete(props, k)
+		}
+	}
+	return props, nil
+}
+
+// previewProperties returns the planned outputs of a function that is about to be created from the given inputs. The
+// computed properties that the inputs leave unset are unknown, with the exception of the custom resource, which can be
+// rendered if none of the inputs are unknown.
+func previewProperties(inputs resource.PropertyMap) (resource.PropertyMap, error) {
+	var f function
+	unknowns, err := decodePartialProperties(inputs, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	computed, err := computedProperties(f)
+	if err != nil {
+		return nil, err
+	}
+	planned := inputs.Copy()
+	for _, name := range computed {
+		if _, ok := planned[resource.PropertyKey(name)]; !ok {
+			planned[resource.PropertyKey(name)] = resource.MakeComputed(resource.NewStringProperty(""))
+		}
+	}
+	if len(unknowns) == 0 {
+		planned["customResource"] = resource.NewStringProperty(f.clientFunction().CustomResource())
+		if resource.NewObjectProperty(inputs).ContainsSecrets() {
+			planned["customResource"] = resource.MakeSecret(planned["customResource"])
+		}
+	}
+	if err = markSecrets(planned, function{}); err != nil {
+		return nil, err
+	}
+	return planned, nil
+}
+
+// previewUpdateProperties returns the planned outputs of a function with the given old outputs that is about to be
+// updated with the given inputs. The outputs are planned as for a new function, except that the computed properties
+// that the update cannot change keep their old values: the function's creation time and invocation count, the network
+// and process that the gateway chose if they are still left unset, and the replica counts if the function's
+// deployment is unchanged.
+func previewUpdateProperties(olds, news resource.PropertyMap) (resource.PropertyMap, error) {
+	planned, err := previewProperties(news)
+	if err != nil {
+		return nil, err
+	}
+
+	olds = olds.Copy()
+	if err = migrateState(olds); err != nil {
+		return nil, err
+	}
+	delete(olds, stateVersionKey)
+	var old, f function
+	if _, err = decodePartialProperties(olds, &old); err != nil {
+		return nil, err
+	}
+	unknowns, err := decodePartialProperties(news, &f)
+	if err != nil {
+		return nil, err
+	}
+	redeployed := len(unknowns) != 0 || !specUnchanged(old.clientFunction(), f.clientFunction()) ||
+		!plainValue(olds["registryAuth"]).DeepEquals(plainValue(news["registryAuth"]))
+
+	keep := []resource.PropertyKey{"createdAt", "invocationCount"}
+	for _, k := range []resource.PropertyKey{"network", "envProcess"} {
+		if _, ok := news[k]; !ok {
+			keep = append(keep, k)
+		}
+	}
+	if !redeployed {
+		keep = append(keep, "replicas", "availableReplicas")
+	}
+	for _, k := range keep {
+		if v, ok := olds[k]; ok {
+			planned[k] = v
+		}
+	}
+	return planned, nil
+}
+
+// readFunction reads the live state of the function with the given service name and namespace. The values of
+// input-only properties are carried over from the given inputs.
+func (p *faasProvider) readFunction(ctx context.Context, c client.FunctionsAPI, service, namespace string,
+	inputs resource.PropertyMap) (resource.PropertyMap, error) {
+
+	var f *client.Function
+	err := p.gatewayCall(ctx, func() (err error) {
+		f, err = c.GetFunction(ctx, service, namespace)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The gateway may report the concrete name of its default namespace. Use the requested namespace so that the
+	// live state agrees with the inputs that produced it.
+	live := makeFunction(f)
+	live.Namespace = namespace
+
+	props, err := liveProperties(live)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range inputOnlyProperties {
+		if v, ok := inputs[k]; ok {
+			props[k] = v
+		}
+	}
+	if err = markSecrets(props, function{}); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// createFunction creates the given function, retrying the creation if it fails due to a transient error. Such an
+// error may be reported after the gateway has created the function, e.g. if the connection is reset before the
+// response arrives, in which case the retry fails because the function already exists. A retry that fails therefore
+// succeeds if the function can be read back.
+func (p *faasProvider) createFunction(ctx context.Context, label string, c client.FunctionsAPI,
+	f *client.Function) error {
+
+	// The gateway clients do not retry the POST that creates a function, as it is not idempotent.
+	attempt := 0
+	return withRetries(ctx, label, p.clientOptions.retryPolicy, func() error {
+		return p.gatewayCall(ctx, func() error {
+			attempt++
+			err := c.CreateFunction(ctx, f)
+			if err != nil && attempt > 1 {
+				if _, getErr := c.GetFunction(ctx, f.Service, f.Namespace); getErr == nil {
+					glog.V(3).Infof("%s: function %v was created by an earlier attempt: %v", label, f.Service, err)
+					return nil
+				}
+			}
+			return err
+		})
+	})
 }
 
-const functionType = "openfaas:system:Function"
+// markSecretOutputs marks the outputs of a function that are derived from the given paths of secret inputs as secret.
+// The custom resource renders every input, so it is secret if any input is.
+func markSecretOutputs(props resource.PropertyMap, secrets []string) {
+	markSecretPaths(props, secrets)
+	if v, ok := props["customResource"]; ok && len(secrets) != 0 && !v.IsSecret() {
+		props["customResource"] = resource.MakeSecret(v)
+	}
+}
+
+// awaitReady waits for the function with the given service name and namespace to have at least one available
+// replica. Unless the context carries a deadline, the wait is bounded by readinessTimeout.
+func (p *faasProvider) awaitReady(ctx context.Context, label string, c client.FunctionsAPI, service,
+	namespace string) error {
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, readinessTimeout)
+		defer cancel()
+	}
+
+	for {
+		var f *client.Function
+		err := p.gatewayCall(ctx, func() (err error) {
+			f, err = c.GetFunction(ctx, service, namespace)
+			return err
+		})
+		switch {
+		case err == nil && f.AvailableReplicas > 0:
+			return nil
+		case err != nil && err != client.ErrNotFound:
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("function %v did not become ready", functionID(service, namespace))
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// driftedProperties returns the sorted paths of the properties whose live values differ from the given inputs.
+func driftedProperties(inputs, live resource.PropertyMap) ([]string, error) {
+	detailedDiff, err := diffLiveProperties(live, inputs, function{})
+	if err != nil {
+		return nil, err
+	}
+	drifted := make([]string, 0, len(detailedDiff))
+	for path := range detailedDiff {
+		drifted = append(drifted, path)
+	}
+	sort.Strings(drifted)
+	return drifted, nil
+}
+
+// deleteBeforeReplace returns true if the function with the given old properties must be deleted before its
+// replacement with the given new properties is created. This is the case if the replacement has the same ID as the
+// existing function and targets the same gateway, as the gateway would otherwise reject the replacement's creation.
+// Users may override this decision using the function's deleteBeforeReplace property.
+func (p *faasProvider) deleteBeforeReplace(olds, news resource.PropertyMap) bool {
+	if v, ok := news["deleteBeforeReplace"]; ok && v.IsBool() {
+		return v.BoolValue()
+	}
+	if oldEndpoint, ok := p.gatewayEndpoint(olds); ok {
+		if newEndpoint, ok := p.gatewayEndpoint(news); ok && newEndpoint != oldEndpoint {
+			return false
+		}
+	}
+	for _, k := range []resource.PropertyKey{"service", "namespace"} {
+		// If the new value is not yet known, assume the worst.
+		if news[k].IsComputed() {
+			return true
+		}
+		if !olds[k].DeepEquals(news[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// partialError creates an error for a resource that was created or updated but whose operation did not complete. The
+// error carries the resource's ID and known state so that the engine can record the resource in the checkpoint.
+func partialError(id string, err error, state *pbstruct.Struct, inputs *pbstruct.Struct) error {
+	detail := &pulumirpc.ErrorResourceInitFailed{
+		Id:         id,
+		Properties: state,
+		Reasons:    []string{err.Error()},
+		Inputs:     inputs,
+	}
+	return rpcerror.WithDetails(rpcerror.New(errorCode(err), err.Error()), detail)
+}
+
+// errorCode returns the gRPC status code that best describes the given error, which may have been returned by the
+// OpenFaaS client. This gives the engine and users an actionable category for gateway failures.
+func errorCode(err error) codes.Code {
+	for err != nil {
+		if client.IsConnectionError(err) {
+			return codes.Unavailable
+		}
+		if status, ok := client.StatusCode(err); ok {
+			switch status {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return codes.PermissionDenied
+			case http.StatusNotFound:
+				return codes.NotFound
+			case http.StatusTooManyRequests:
+				return codes.ResourceExhausted
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				return codes.Unavailable
+			}
+			return codes.Unknown
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return codes.Unknown
+}
+
+// gatewayError converts an error returned by the OpenFaaS client into a gRPC error with an appropriate status code.
+func gatewayError(err error) error {
+	return rpcerror.New(errorCode(err), err.Error())
+}
 
 // Check validates that the given property bag is valid for a resource of the given type and returns
 // the inputs that should be passed to successive calls to Diff, Create, or Update for this
@@ -131,25 +906,105 @@ func (p *faasProvider) Check(ctx context.Context, req *pulumirpc.CheckRequest) (
 	label := fmt.Sprintf("%s.Check(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
 	news, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
-		Label: fmt.Sprintf("%s.news", label), KeepUnknowns: true, SkipNulls: true,
+		Label: fmt.Sprintf("%s.news", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// If enabled, accept inputs that follow other naming conventions, e.g. those of stack.yml files.
+	if p.lenientPropertyKeys {
+		if err = foldPropertyKeys(news, function{}); err != nil {
+			return nil, err
+		}
+	}
+
+	// If enabled, accept strings for boolean and numeric properties, e.g. from YAML programs. Each conversion is
+	// reported as a warning so that the program can be fixed.
+	var coerced []string
+	if p.coerceStrings {
+		if coerced, err = coerceStrings(news, function{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.checkDeprecations(ctx, urn, news, functionDeprecations); err != nil {
+		return nil, err
+	}
+
+	// Deploy functions that do not specify a namespace to the provider's default namespace, if any.
+	if _, ok := news["namespace"]; !ok && p.namespace != "" {
+		news["namespace"] = resource.NewStringProperty(p.namespace)
+	}
+
+	// Merge in the provider's default labels and annotations. This is done here rather than at deployment time so that
+	// the defaults are visible in previews and diffs remain stable.
+	mergeDefaults(news, "labels", p.defaultLabels)
+	mergeDefaults(news, "annotations", p.defaultAnnotations)
+
+	// Fill in the schema's default values for the same reason.
+	if err = applyDefaults(news, function{}); err != nil {
+		return nil, err
+	}
+
 	// Check the schema.
-	failures, err := checkProperties(news, function{})
+	failures, warnings, err := checkPropertiesWithWarnings(news, function{})
 	if err != nil {
 		return nil, err
 	}
+	for _, msg := range append(coerced, warnings...) {
+		if err = p.warn(ctx, urn, msg); err != nil {
+			return nil, err
+		}
+	}
+	failures = append(failures, p.checkGatewayProfile(news)...)
+	for _, k := range outputOnlyProperties {
+		if _, ok := news[k]; ok {
+			failures = append(failures, &pulumirpc.CheckFailure{
+				Property: string(k), Reason: "property is reported by the gateway and cannot be set",
+			})
+		}
+	}
 
-	// We currently don't change the inputs during check.
-	return &pulumirpc.CheckResponse{Inputs: req.GetNews(), Failures: failures}, nil
+	inputs, err := plugin.MarshalProperties(news, plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.inputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pulumirpc.CheckResponse{Inputs: inputs, Failures: failures}, nil
+}
+
+// mergeDefaults adds the given default entries to the map-valued property with the given key. Entries that are
+// already present in the property take precedence over the defaults. If the property's value is unknown or is not an
+// object, it is left unchanged.
+func mergeDefaults(props resource.PropertyMap, key resource.PropertyKey, defaults map[string]string) {
+	if len(defaults) == 0 {
+		return
+	}
+
+	var merged resource.PropertyMap
+	switch v := props[key]; {
+	case v.IsNull():
+		merged = resource.PropertyMap{}
+	case v.IsObject():
+		merged = v.ObjectValue().Copy()
+	default:
+		return
+	}
+
+	for k, v := range defaults {
+		if _, ok := merged[resource.PropertyKey(k)]; !ok {
+			merged[resource.PropertyKey(k)] = resource.NewStringProperty(v)
+		}
+	}
+	props[key] = resource.NewObjectProperty(merged)
 }
 
 // Diff checks what impacts a hypothetical update will have on the resource's properties.
@@ -158,16 +1013,19 @@ func (p *faasProvider) Diff(ctx context.Context, req *pulumirpc.DiffRequest) (*p
 	label := fmt.Sprintf("%s.Diff(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
 	olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{
-		Label: fmt.Sprintf("%s.news", label), KeepUnknowns: true, SkipNulls: true,
+		Label: fmt.Sprintf("%s.olds", label), KeepUnknowns: true, SkipNulls: true,
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err = migrateState(olds); err != nil {
+		return nil, err
+	}
 
 	news, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
 		Label: fmt.Sprintf("%s.news", label), KeepUnknowns: true, SkipNulls: true,
@@ -177,7 +1035,7 @@ func (p *faasProvider) Diff(ctx context.Context, req *pulumirpc.DiffRequest) (*p
 	}
 
 	// Diff the values.
-	changed, replaces, err := diffProperties(olds, news, function{})
+	changed, replaces, detailedDiff, err := diffProperties(olds, news, function{})
 	if err != nil {
 		return nil, err
 	}
@@ -187,104 +1045,264 @@ func (p *faasProvider) Diff(ctx context.Context, req *pulumirpc.DiffRequest) (*p
 		diff = pulumirpc.DiffResponse_DIFF_SOME
 	}
 
+	// Report the top-level properties that changed alongside the detailed diff.
+	var diffs []string
+	seen := map[string]bool{}
+	for path := range detailedDiff {
+		key := path
+		if i := strings.IndexAny(path, ".["); i != -1 {
+			key = path[:i]
+		}
+		if !seen[key] {
+			seen[key] = true
+			diffs = append(diffs, key)
+		}
+	}
+	sort.Strings(diffs)
+
+	// Properties that force a replacement cannot change as part of an update, so they are stable unless the function
+	// is being replaced.
+	stables := []string{}
+	if len(replaces) == 0 {
+		if stables, err = forceNewProperties(function{}); err != nil {
+			return nil, err
+		}
+	}
+
 	return &pulumirpc.DiffResponse{
 		Changes:             diff,
 		Replaces:            replaces,
-		Stables:             []string{},
-		DeleteBeforeReplace: false,
+		Stables:             stables,
+		DeleteBeforeReplace: len(replaces) != 0 && p.deleteBeforeReplace(olds, news),
+		Diffs:               diffs,
+		DetailedDiff:        detailedDiff,
+		HasDetailedDiff:     true,
 	}, nil
 }
 
 // Create allocates a new instance of the provided resource and returns its unique ID afterwards.
 // (The input ID must be blank.)  If this call fails, the resource must not have been created (i.e.,
-// it is "transacational").
+// it is "transacational"), unless the failure is reported as a partial failure via partialError.
 func (p *faasProvider) Create(ctx context.Context, req *pulumirpc.CreateRequest) (*pulumirpc.CreateResponse, error) {
 	urn := resource.URN(req.GetUrn())
 	label := fmt.Sprintf("%s.Create(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
+	done, err := p.canceler.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	newResInputs, err := plugin.UnmarshalProperties(req.GetProperties(), plugin.MarshalOptions{
-		Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true,
+		Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// During previews, leave the gateway untouched: the planned outputs are the inputs, any of which may be unknown,
+	// together with the properties that the gateway will report.
+	if req.GetPreview() {
+		planned, err := previewProperties(newResInputs)
+		if err != nil {
+			return nil, err
+		}
+		outputs, err := plugin.MarshalProperties(planned, plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &pulumirpc.CreateResponse{Properties: outputs}, nil
+	}
+	if p.offline {
+		return nil, errOffline
+	}
+
 	var f function
-	if err := decodeProperties(newResInputs, &f); err != nil {
+	secrets, err := decodeSecretProperties(newResInputs, &f)
+	if err != nil {
+		return nil, err
+	}
+	g, err := p.gatewayFromProperties(newResInputs)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
 		return nil, err
 	}
 
-	clientFunc := &client.Function{
-		Service:      f.Service,
-		Network:      f.Network,
-		Image:        f.Image,
-		EnvProcess:   f.EnvProcess,
-		EnvVars:      f.EnvVars,
-		Labels:       f.Labels,
-		Annotations:  f.Annotations,
-		Secrets:      f.Secrets,
-		RegistryAuth: f.RegistryAuth,
+	timeout := p.operationTimeout(req.GetTimeout())
+	opCtx, cancel := p.operationContext(ctx, timeout)
+	defer cancel()
+
+	if err = p.createFunction(opCtx, label, c, f.clientFunction()); err != nil {
+		return nil, gatewayError(timeoutError(opCtx, err, "create", timeout))
 	}
 
-	if err := p.client.CreateFunction(p.canceler.context, clientFunc); err != nil {
-		return nil, err
+	// The function now exists. Any failures past this point must be reported as partial failures so that the engine
+	// records the function in the checkpoint rather than orphaning it.
+	id := functionID(f.Service, f.Namespace)
+
+	// Wait for the function to become ready. If this function is replacing another, this ensures that the function
+	// it replaces is not deleted until this function is able to serve its traffic.
+	if !f.SkipAwait {
+		if err := p.awaitReady(opCtx, label, c, f.Service, f.Namespace); err != nil {
+			return nil, partialError(id, timeoutError(opCtx, err, "create", timeout),
+				req.GetProperties(), req.GetProperties())
+		}
 	}
 
-	return &pulumirpc.CreateResponse{
-		Id: f.Service, Properties: req.GetProperties(),
-	}, nil
+	props, err := p.readFunction(opCtx, c, f.Service, f.Namespace, newResInputs)
+	if err != nil {
+		return nil, partialError(id, timeoutError(opCtx, err, "create", timeout),
+			req.GetProperties(), req.GetProperties())
+	}
+	markSecretOutputs(props, secrets)
+
+	outputs, err := plugin.MarshalProperties(versionedState(props), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, partialError(id, err, req.GetProperties(), req.GetProperties())
+	}
+
+	return &pulumirpc.CreateResponse{Id: id, Properties: outputs}, nil
 }
 
 // Read the current live state associated with a resource.  Enough state must be include in the
 // inputs to uniquely identify the resource; this is typically just the resource ID, but may also
 // include some properties.
+//
+// If the request carries no inputs (as is the case for `pulumi import`), the inputs are computed from the function's
+// live state so that the function can be adopted into a stack.
 func (p *faasProvider) Read(ctx context.Context, req *pulumirpc.ReadRequest) (*pulumirpc.ReadResponse, error) {
 	urn := resource.URN(req.GetUrn())
-	label := fmt.Sprintf("%s.Update(%s)", p.label(), urn)
+	label := fmt.Sprintf("%s.Read(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
-	f, err := p.client.GetFunction(p.canceler.context, req.GetId())
+	done, err := p.canceler.begin()
 	if err != nil {
 		return nil, err
 	}
+	defer done()
 
-	// TODO: encode response
-	props, err := encodeProperties(function{
-		Service:      f.Service,
-		Network:      f.Network,
-		Image:        f.Image,
-		EnvProcess:   f.EnvProcess,
-		EnvVars:      f.EnvVars,
-		Labels:       f.Labels,
-		Annotations:  f.Annotations,
-		Secrets:      f.Secrets,
-		RegistryAuth: f.RegistryAuth,
+	// When offline, assume that the function is unchanged. Functions that are being imported cannot be read at all.
+	if p.offline {
+		if len(req.GetInputs().GetFields()) == 0 {
+			return nil, errOffline
+		}
+		return &pulumirpc.ReadResponse{Id: req.GetId(), Properties: req.GetProperties(), Inputs: req.GetInputs()}, nil
+	}
+
+	oldInputs, err := plugin.UnmarshalProperties(req.GetInputs(), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.inputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
 	})
+	if err != nil {
+		return nil, err
+	}
+	if len(oldInputs) != 0 {
+		if err = migrateState(oldInputs); err != nil {
+			return nil, err
+		}
+	}
+
+	service, namespace := parseFunctionID(req.GetId())
+	// Prefer the gateway recorded in the inputs, falling back to the one recorded in the outputs.
+	gatewayProps := oldInputs
+	if len(gatewayProps) == 0 {
+		gatewayProps, err = plugin.UnmarshalProperties(req.GetProperties(), plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = migrateState(gatewayProps); err != nil {
+			return nil, err
+		}
+	}
+	g, err := p.gatewayFromProperties(gatewayProps)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outputs that are derived from secret inputs must stay secret across refreshes.
+	var recorded function
+	secrets, err := decodeSecretProperties(gatewayProps, &recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := p.readFunction(p.canceler.context, c, service, namespace, gatewayProps)
 	switch {
 	case err == client.ErrNotFound:
 		// If the function was not found, return an empty response to indicate that it has been deleted.
 		return &pulumirpc.ReadResponse{}, nil
 	case err != nil:
-		return nil, err
+		return nil, gatewayError(err)
 	}
+	markSecretOutputs(props, secrets)
 
-	outputs, err := plugin.MarshalProperties(props, plugin.MarshalOptions{
-		Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true,
+	outputs, err := plugin.MarshalProperties(versionedState(props), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &pulumirpc.ReadResponse{Id: req.GetId(), Properties: outputs}, nil
+	// If this is a refresh, let the user know about any changes that were made outside of Pulumi. The recorded inputs
+	// are returned unchanged apart from their migration to the current schema, so the next update will converge the
+	// function back to its declared state.
+	var inputs *pbstruct.Struct
+	if len(oldInputs) != 0 {
+		migratedInputs := oldInputs.Copy()
+		delete(migratedInputs, stateVersionKey)
+		inputs, err = plugin.MarshalProperties(migratedInputs, plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.inputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		drifted, err := driftedProperties(oldInputs, props)
+		if err != nil {
+			return nil, err
+		}
+		if len(drifted) != 0 {
+			msg := fmt.Sprintf("function %v has drifted from its declared state (%v changed outside of Pulumi); "+
+				"run `pulumi up` to restore it", req.GetId(), strings.Join(drifted, ", "))
+			if err = p.warn(ctx, urn, msg); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		importedInputs := props.Copy()
+		for _, k := range outputOnlyProperties {
+			delete(importedInputs, k)
+		}
+		inputs, err = plugin.MarshalProperties(importedInputs, plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.inputs", label), KeepUnknowns: true, SkipNulls: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &pulumirpc.ReadResponse{Id: req.GetId(), Properties: outputs, Inputs: inputs}, nil
 }
 
 // Update updates an existing resource with new values.
@@ -293,39 +1311,112 @@ func (p *faasProvider) Update(ctx context.Context, req *pulumirpc.UpdateRequest)
 	label := fmt.Sprintf("%s.Update(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
+	done, err := p.canceler.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	newResInputs, err := plugin.UnmarshalProperties(req.GetNews(), plugin.MarshalOptions{
-		Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true,
+		Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// During previews, leave the gateway untouched: the planned outputs are the inputs, any of which may be unknown,
+	// together with the properties that the gateway will report, which keep their old values where the update cannot
+	// change them.
+	if req.GetPreview() {
+		olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.olds", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		planned, err := previewUpdateProperties(olds, newResInputs)
+		if err != nil {
+			return nil, err
+		}
+		outputs, err := plugin.MarshalProperties(planned, plugin.MarshalOptions{
+			Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &pulumirpc.UpdateResponse{Properties: outputs}, nil
+	}
+	if p.offline {
+		return nil, errOffline
+	}
+
 	var f function
-	if err := decodeProperties(newResInputs, &f); err != nil {
+	secrets, err := decodeSecretProperties(newResInputs, &f)
+	if err != nil {
 		return nil, err
 	}
-
-	clientFunc := &client.Function{
-		Service:      f.Service,
-		Network:      f.Network,
-		Image:        f.Image,
-		EnvProcess:   f.EnvProcess,
-		EnvVars:      f.EnvVars,
-		Labels:       f.Labels,
-		Annotations:  f.Annotations,
-		Secrets:      f.Secrets,
-		RegistryAuth: f.RegistryAuth,
+	g, err := p.gatewayFromProperties(newResInputs)
+	if err != nil {
+		return nil, err
 	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.operationTimeout(req.GetTimeout())
+	opCtx, cancel := p.operationContext(ctx, timeout)
+	defer cancel()
 
-	if err := p.client.UpdateFunction(p.canceler.context, clientFunc); err != nil {
+	olds, err := plugin.UnmarshalProperties(req.GetOlds(), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.olds", label), KeepUnknowns: true, SkipNulls: true,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &pulumirpc.UpdateResponse{Properties: req.GetNews()}, nil
+	// Updating a function restarts its replicas, so skip the update if the live function already matches. This is
+	// common when a diff is caused by refresh noise rather than by a change to the program. If the live function
+	// cannot be read, update it regardless.
+	desired := f.clientFunction()
+	var live *client.Function
+	err = p.gatewayCall(opCtx, func() (err error) {
+		live, err = c.GetFunction(opCtx, f.Service, f.Namespace)
+		return err
+	})
+	if err == nil && specUnchanged(live, desired) &&
+		plainValue(olds["registryAuth"]).DeepEquals(plainValue(newResInputs["registryAuth"])) {
+		glog.V(5).Infof("%s: live function matches the desired spec; skipping update", label)
+	} else {
+		err = p.gatewayCall(opCtx, func() error {
+			return c.UpdateFunction(opCtx, desired)
+		})
+		if err != nil {
+			return nil, gatewayError(timeoutError(opCtx, err, "update", timeout))
+		}
+	}
+
+	// The update has been applied. Return the gateway's view of the function so that the outputs reflect any defaults
+	// or normalization applied by the gateway.
+	props, err := p.readFunction(opCtx, c, f.Service, f.Namespace, newResInputs)
+	if err != nil {
+		return nil, partialError(req.GetId(), timeoutError(opCtx, err, "update", timeout),
+			req.GetNews(), req.GetNews())
+	}
+	markSecretOutputs(props, secrets)
+
+	outputs, err := plugin.MarshalProperties(versionedState(props), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.outputs", label), KeepUnknowns: true, SkipNulls: true, KeepSecrets: true,
+	})
+	if err != nil {
+		return nil, partialError(req.GetId(), err, req.GetNews(), req.GetNews())
+	}
+
+	return &pulumirpc.UpdateResponse{Properties: outputs}, nil
 }
 
 // Delete tears down an existing resource with the given ID.  If it fails, the resource is assumed
@@ -335,13 +1426,49 @@ func (p *faasProvider) Delete(ctx context.Context, req *pulumirpc.DeleteRequest)
 	label := fmt.Sprintf("%s.Delete(%s)", p.label(), urn)
 	glog.V(9).Infof("%s executing", label)
 
-	if urn.Type() != functionType {
+	if !isFunctionType(urn.Type()) {
 		return nil, errors.Errorf("unknown resource type %v", urn.Type())
 	}
 
-	if err := p.client.DeleteFunction(p.canceler.context, req.GetId()); err != nil {
+	done, err := p.canceler.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if p.offline {
+		return nil, errOffline
+	}
+
+	props, err := plugin.UnmarshalProperties(req.GetProperties(), plugin.MarshalOptions{
+		Label: fmt.Sprintf("%s.properties", label), KeepUnknowns: true, SkipNulls: true,
+	})
+	if err != nil {
 		return nil, err
 	}
+	if err = migrateState(props); err != nil {
+		return nil, err
+	}
+	g, err := p.gatewayFromProperties(props)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.clientFor(g)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.operationTimeout(req.GetTimeout())
+	opCtx, cancel := p.operationContext(ctx, timeout)
+	defer cancel()
+
+	service, namespace := parseFunctionID(req.GetId())
+	err = p.gatewayCall(opCtx, func() error {
+		return c.DeleteFunction(opCtx, service, namespace)
+	})
+	if err != nil {
+		return nil, gatewayError(timeoutError(opCtx, err, "delete", timeout))
+	}
 
 	return &pbempty.Empty{}, nil
 }
@@ -353,8 +1480,10 @@ func (p *faasProvider) GetPluginInfo(context.Context, *pbempty.Empty) (*pulumirp
 	}, nil
 }
 
-// Cancel signals the provider to gracefully shut down and abort any ongoing resource operations.
+// Cancel signals the provider to gracefully shut down and abort any ongoing resource operations. New operations are
+// rejected immediately, while operations that are already in flight are given a short window to complete before they
+// are aborted.
 func (p *faasProvider) Cancel(context.Context, *pbempty.Empty) (*pbempty.Empty, error) {
-	p.canceler.cancel()
+	p.canceler.drain(cancelDrainTimeout)
 	return &pbempty.Empty{}, nil
 }
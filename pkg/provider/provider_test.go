@@ -0,0 +1,719 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	pulumirpc "github.com/pulumi/pulumi/sdk/proto/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+	"github.com/pulumi/pulumi-openfaas/pkg/client/fake"
+)
+
+const testFunctionURN = "urn:pulumi:test::test::openfaas:index:Function::echo"
+
+// newTestProvider creates a provider that is configured to use the given fake gateway.
+func newTestProvider(faas *fake.Client, config map[string]string) (*faasProvider, error) {
+	p := &faasProvider{
+		canceler: makeCancellationContext(),
+		name:     "openfaas",
+		version:  "0.0.1",
+		newClient: func(context.Context, gateway, clientOptions) (client.FunctionsAPI, error) {
+			return faas, nil
+		},
+	}
+
+	vars := map[string]string{"openfaas:config:endpoint": "http://gateway.test:8080"}
+	for k, v := range config {
+		vars["openfaas:config:"+k] = v
+	}
+	_, err := p.Configure(context.Background(), &pulumirpc.ConfigureRequest{Variables: vars})
+	return p, err
+}
+
+// checkRequest creates a Check request for a function with the given inputs.
+func checkRequest(t *testing.T, props resource.PropertyMap) *pulumirpc.CheckRequest {
+	s, err := plugin.MarshalProperties(props, plugin.MarshalOptions{KeepUnknowns: true, SkipNulls: true})
+	assert.NoError(t, err)
+	return &pulumirpc.CheckRequest{Urn: testFunctionURN, News: s}
+}
+
+func TestConfigureHealthCheck(t *testing.T) {
+	faas := fake.NewClient()
+	faas.HealthError = errors.New("connection refused")
+
+	_, err := newTestProvider(faas, nil)
+	assert.Error(t, err)
+
+	_, err = newTestProvider(faas, map[string]string{"skipHealthCheck": "true"})
+	assert.NoError(t, err)
+}
+
+func TestOfflineCredentialHelper(t *testing.T) {
+	g := gateway{Endpoint: "http://gateway.test:8080", CredentialCommand: "/nonexistent/credential-helper"}
+
+	_, err := newGatewayClient(context.Background(), g, clientOptions{})
+	assert.Error(t, err)
+
+	_, err = newGatewayClient(context.Background(), g, clientOptions{offline: true})
+	assert.NoError(t, err)
+}
+
+func TestHTTP2(t *testing.T) {
+	g := gateway{Endpoint: "https://gateway.test"}
+
+	c, err := newHTTPClient(g, clientOptions{})
+	if assert.NoError(t, err) {
+		assert.Contains(t, c.Transport.(*http.Transport).TLSNextProto, "h2")
+	}
+
+	c, err = newHTTPClient(g, clientOptions{disableHTTP2: true})
+	if assert.NoError(t, err) {
+		nextProto := c.Transport.(*http.Transport).TLSNextProto
+		assert.NotNil(t, nextProto)
+		assert.Empty(t, nextProto)
+	}
+}
+
+func TestGatewayClientRetries(t *testing.T) {
+	requests := 0
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, err := w.Write([]byte(`{"name":"echo","image":"functions/alpine:latest"}`))
+		assert.NoError(t, err)
+	}))
+	defer gw.Close()
+
+	policy := client.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}
+	c, err := newGatewayClient(context.Background(), gateway{Endpoint: gw.URL}, clientOptions{retryPolicy: policy})
+	if !assert.NoError(t, err) {
+		return
+	}
+	f, err := c.GetFunction(context.Background(), "echo", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "echo", f.Service)
+	}
+	assert.Equal(t, 2, requests)
+}
+
+func TestRedactConfig(t *testing.T) {
+	redacted := redactConfig(map[string]string{
+		"openfaas:config:endpoint": "http://gateway.test:8080",
+		"openfaas:config:password": "hunter2",
+		"openfaas:config:headers":  `{"CF-Access-Client-Secret":"correct horse"}`,
+		"openfaas:config:gateways": `{"staging":{"endpoint":"http://staging.test:8080","password":"swordfish"}}`,
+	})
+	assert.Equal(t, "http://gateway.test:8080", redacted["openfaas:config:endpoint"])
+	assert.Equal(t, "[secret]", redacted["openfaas:config:password"])
+	assert.Equal(t, "[secret]", redacted["openfaas:config:headers"])
+	assert.Contains(t, redacted["openfaas:config:gateways"], "http://staging.test:8080")
+	assert.NotContains(t, redacted["openfaas:config:gateways"], "swordfish")
+
+	redacted = redactConfig(map[string]string{"openfaas:config:gateways": `{"staging":"swordfish"}`})
+	assert.Equal(t, "[secret]", redacted["openfaas:config:gateways"])
+}
+
+func TestFunctionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient()
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	check, err := p.Check(ctx, checkRequest(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service": "echo",
+		"image":   "ghcr.io/openfaas/alpine:latest",
+	})))
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+
+	created, err := p.Create(ctx, &pulumirpc.CreateRequest{Urn: testFunctionURN, Properties: check.GetInputs()})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "echo", created.GetId())
+	if functions := faas.Functions(); assert.Len(t, functions, 1) {
+		assert.Equal(t, "echo", functions[0].Service)
+		assert.Equal(t, "ghcr.io/openfaas/alpine:latest", functions[0].Image)
+	}
+
+	read, err := p.Read(ctx, &pulumirpc.ReadRequest{
+		Id: created.GetId(), Urn: testFunctionURN, Properties: created.GetProperties(), Inputs: check.GetInputs(),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, created.GetId(), read.GetId())
+
+	_, err = p.Delete(ctx, &pulumirpc.DeleteRequest{
+		Id: created.GetId(), Urn: testFunctionURN, Properties: created.GetProperties(),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, faas.Functions())
+}
+
+func TestCreateRetryAfterLostResponse(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient()
+	faas.DroppedCreateResponses = 1
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	check, err := p.Check(ctx, checkRequest(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service": "echo",
+		"image":   "ghcr.io/openfaas/alpine:latest",
+	})))
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+
+	created, err := p.Create(ctx, &pulumirpc.CreateRequest{Urn: testFunctionURN, Properties: check.GetInputs()})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "echo", created.GetId())
+	assert.Len(t, faas.Functions(), 1)
+}
+
+func TestReadMigratesInputs(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient(client.Function{
+		Service: "echo", Image: "ghcr.io/openfaas/alpine:latest", Labels: map[string]string{"team": "platform"},
+	})
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Inputs recorded before schema version 1 hold labels as lists of key=value strings.
+	legacy, err := plugin.MarshalProperties(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service": "echo",
+		"image":   "ghcr.io/openfaas/alpine:latest",
+		"labels":  []interface{}{"team=platform"},
+	}), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	read, err := p.Read(ctx, &pulumirpc.ReadRequest{
+		Id: "echo", Urn: testFunctionURN, Properties: legacy, Inputs: legacy,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	inputs, err := plugin.UnmarshalProperties(read.GetInputs(), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.True(t, inputs["labels"].IsObject()) {
+		assert.Equal(t, "platform", inputs["labels"].ObjectValue()["team"].StringValue())
+	}
+	assert.NotContains(t, inputs, stateVersionKey)
+}
+
+func TestReadDriftedFunction(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient(client.Function{Service: "echo", Image: "ghcr.io/openfaas/alpine:3.9"})
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The warning about the drifted image is logged rather than reported, as the provider has no engine host.
+	inputs, err := plugin.MarshalProperties(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service": "echo",
+		"image":   "ghcr.io/openfaas/alpine:latest",
+	}), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	read, err := p.Read(ctx, &pulumirpc.ReadRequest{
+		Id: "echo", Urn: testFunctionURN, Properties: inputs, Inputs: inputs,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	props, err := plugin.UnmarshalProperties(read.GetProperties(), plugin.MarshalOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ghcr.io/openfaas/alpine:3.9", props["image"].StringValue())
+	}
+}
+
+func TestDeleteNamespacedFunction(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient(
+		client.Function{Service: "echo", Namespace: "staging"},
+		client.Function{Service: "echo", Namespace: "production"},
+	)
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = p.Delete(ctx, &pulumirpc.DeleteRequest{Id: functionID("echo", "staging"), Urn: testFunctionURN})
+	assert.NoError(t, err)
+	if functions := faas.Functions(); assert.Len(t, functions, 1) {
+		assert.Equal(t, "production", functions[0].Namespace)
+	}
+}
+
+func TestDeleteBeforeReplace(t *testing.T) {
+	p := &faasProvider{
+		endpoint: "http://gateway.test:8080",
+		gatewayProfiles: map[string]gateway{
+			"staging":    {Endpoint: "http://staging.test:8080"},
+			"production": {Endpoint: "http://gateway.test:8080/"},
+		},
+	}
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"service": "echo"})
+
+	tests := []struct {
+		name   string
+		news   map[string]interface{}
+		delete bool
+	}{
+		{name: "same service", news: map[string]interface{}{"service": "echo"}, delete: true},
+		{name: "renamed", news: map[string]interface{}{"service": "echo2"}, delete: false},
+		{name: "other namespace", news: map[string]interface{}{"service": "echo", "namespace": "staging"}},
+		{name: "other profile", news: map[string]interface{}{"service": "echo", "gatewayProfile": "staging"}},
+		{name: "profile for the same gateway", news: map[string]interface{}{
+			"service": "echo", "gatewayProfile": "production",
+		}, delete: true},
+		{name: "other gateway", news: map[string]interface{}{
+			"service": "echo", "gateway": map[string]interface{}{"endpoint": "http://other.test:8080"},
+		}},
+		{name: "unknown gateway", news: map[string]interface{}{
+			"service": "echo", "gateway": resource.Computed{Element: resource.NewObjectProperty(nil)},
+		}, delete: true},
+		{name: "unknown service", news: map[string]interface{}{
+			"service": resource.Computed{Element: resource.NewStringProperty("")}, "gatewayProfile": "production",
+		}, delete: true},
+		{name: "overridden", news: map[string]interface{}{"service": "echo", "deleteBeforeReplace": false}},
+	}
+	for _, tt := range tests {
+		news := resource.NewPropertyMapFromMap(tt.news)
+		assert.Equal(t, tt.delete, p.deleteBeforeReplace(olds, news), tt.name)
+	}
+}
+
+func TestPreviewCreateWithUnknowns(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient()
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{"service": "echo"})
+	inputs["image"] = resource.MakeComputed(resource.NewStringProperty(""))
+	s, err := plugin.MarshalProperties(inputs, plugin.MarshalOptions{KeepUnknowns: true, SkipNulls: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	created, err := p.Create(ctx, &pulumirpc.CreateRequest{Urn: testFunctionURN, Properties: s, Preview: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, faas.Functions())
+
+	outputs, err := plugin.UnmarshalProperties(created.GetProperties(), plugin.MarshalOptions{KeepUnknowns: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "echo", outputs["service"].StringValue())
+	assert.True(t, outputs["image"].IsComputed())
+	assert.True(t, outputs["replicas"].IsComputed())
+	assert.True(t, outputs["customResource"].IsComputed())
+}
+
+func TestPreviewUpdate(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient()
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service":           "echo",
+		"image":             "ghcr.io/openfaas/alpine:1",
+		"network":           "func_functions",
+		"labels":            map[string]interface{}{"team": "platform"},
+		"replicas":          2,
+		"availableReplicas": 2,
+		"invocationCount":   42,
+		"createdAt":         "2019-01-01T00:00:00Z",
+		"customResource":    "kind: Function",
+	})
+	oldState, err := plugin.MarshalProperties(versionedState(olds), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	labels := map[string]interface{}{"team": "platform"}
+	tests := []struct {
+		name       string
+		news       resource.PropertyMap
+		redeployed bool
+	}{
+		{name: "unchanged deployment", news: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"service": "echo", "image": "ghcr.io/openfaas/alpine:1", "labels": labels,
+			"skipAwait": true,
+		})},
+		{name: "new image", news: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"service": "echo", "image": "ghcr.io/openfaas/alpine:2", "labels": labels,
+		}), redeployed: true},
+		{name: "unknown image", news: resource.PropertyMap{
+			"service": resource.NewStringProperty("echo"),
+			"image":   resource.MakeComputed(resource.NewStringProperty("")),
+		}, redeployed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			news, err := plugin.MarshalProperties(tt.news, plugin.MarshalOptions{KeepUnknowns: true, SkipNulls: true})
+			if !assert.NoError(t, err) {
+				return
+			}
+			updated, err := p.Update(ctx, &pulumirpc.UpdateRequest{
+				Id: "echo", Urn: testFunctionURN, Olds: oldState, News: news, Preview: true,
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			outputs, err := plugin.UnmarshalProperties(updated.GetProperties(), plugin.MarshalOptions{
+				KeepUnknowns: true,
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "2019-01-01T00:00:00Z", outputs["createdAt"].StringValue())
+			assert.Equal(t, float64(42), outputs["invocationCount"].NumberValue())
+			assert.Equal(t, "func_functions", outputs["network"].StringValue())
+			assert.True(t, outputs["envProcess"].IsComputed())
+			if tt.redeployed {
+				assert.True(t, outputs["replicas"].IsComputed())
+				assert.True(t, outputs["availableReplicas"].IsComputed())
+			} else {
+				assert.Equal(t, float64(2), outputs["replicas"].NumberValue())
+				assert.Equal(t, float64(2), outputs["availableReplicas"].NumberValue())
+			}
+			if tt.news["image"].IsComputed() {
+				assert.True(t, outputs["customResource"].IsComputed())
+			} else {
+				assert.Contains(t, outputs["customResource"].StringValue(), tt.news["image"].StringValue())
+			}
+		})
+	}
+	assert.Empty(t, faas.Functions())
+}
+
+func TestSpecUnchanged(t *testing.T) {
+	live := client.Function{
+		Service:           "echo",
+		Image:             "functions/alpine:latest",
+		Network:           "func_functions",
+		EnvProcess:        "cat",
+		EnvVars:           map[string]string{"LOG_LEVEL": "debug"},
+		Labels:            map[string]string{"team": "payments"},
+		Secrets:           []string{"db-password", "api-key"},
+		Replicas:          3,
+		AvailableReplicas: 2,
+	}
+
+	tests := []struct {
+		name      string
+		desired   func(f *client.Function)
+		unchanged bool
+	}{
+		{name: "identical", desired: func(f *client.Function) {}, unchanged: true},
+		{name: "defaults left to the gateway", desired: func(f *client.Function) {
+			f.Network, f.EnvProcess = "", ""
+		}, unchanged: true},
+		{name: "reordered secrets", desired: func(f *client.Function) {
+			f.Secrets = []string{"api-key", "db-password"}
+		}, unchanged: true},
+		{name: "status and registry credentials", desired: func(f *client.Function) {
+			f.Replicas, f.AvailableReplicas, f.RegistryAuth = 0, 0, "dXNlcjpwYXNz"
+		}, unchanged: true},
+		{name: "image", desired: func(f *client.Function) { f.Image = "functions/alpine:3.9" }},
+		{name: "network", desired: func(f *client.Function) { f.Network = "other" }},
+		{name: "env var value", desired: func(f *client.Function) {
+			f.EnvVars = map[string]string{"LOG_LEVEL": "info"}
+		}},
+		{name: "removed label", desired: func(f *client.Function) { f.Labels = nil }},
+		{name: "added annotation", desired: func(f *client.Function) {
+			f.Annotations = map[string]string{"topic": "orders"}
+		}},
+		{name: "replaced secret", desired: func(f *client.Function) {
+			f.Secrets = []string{"db-password", "other-key"}
+		}},
+	}
+	for _, tt := range tests {
+		desired := live
+		tt.desired(&desired)
+		assert.Equal(t, tt.unchanged, specUnchanged(&live, &desired), tt.name)
+	}
+}
+
+func TestCheckUpgradesDeprecatedLists(t *testing.T) {
+	p, err := newTestProvider(fake.NewClient(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The deprecation warnings are logged rather than reported, as the provider has no engine host.
+	check, err := p.Check(context.Background(), checkRequest(t, resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service":     "echo",
+		"image":       "ghcr.io/openfaas/alpine:latest",
+		"labels":      []interface{}{"team=platform"},
+		"annotations": []interface{}{"topic=orders"},
+	})))
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+	inputs, err := plugin.UnmarshalProperties(check.GetInputs(), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.True(t, inputs["labels"].IsObject()) {
+		assert.Equal(t, "platform", inputs["labels"].ObjectValue()["team"].StringValue())
+	}
+	if assert.True(t, inputs["annotations"].IsObject()) {
+		assert.Equal(t, "orders", inputs["annotations"].ObjectValue()["topic"].StringValue())
+	}
+}
+
+func TestLenientPropertyKeys(t *testing.T) {
+	ctx := context.Background()
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"Service":  "echo",
+		"image":    "ghcr.io/openfaas/alpine:latest",
+		"env_vars": map[string]interface{}{"write_debug": "true"},
+	})
+
+	p, err := newTestProvider(fake.NewClient(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	check, err := p.Check(ctx, checkRequest(t, inputs))
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, check.GetFailures())
+	}
+
+	p, err = newTestProvider(fake.NewClient(), map[string]string{"lenientPropertyKeys": "true"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	check, err = p.Check(ctx, checkRequest(t, inputs))
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+	checked, err := plugin.UnmarshalProperties(check.GetInputs(), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "echo", checked["service"].StringValue())
+	assert.Equal(t, "true", checked["envVars"].ObjectValue()["write_debug"].StringValue())
+	assert.NotContains(t, checked, resource.PropertyKey("env_vars"))
+}
+
+func TestCoerceStringInputs(t *testing.T) {
+	ctx := context.Background()
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"service":   "echo",
+		"image":     "ghcr.io/openfaas/alpine:latest",
+		"skipAwait": "true",
+	})
+
+	p, err := newTestProvider(fake.NewClient(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	check, err := p.Check(ctx, checkRequest(t, inputs))
+	if assert.NoError(t, err) {
+		assert.Len(t, check.GetFailures(), 1)
+	}
+
+	p, err = newTestProvider(fake.NewClient(), map[string]string{"coerceStrings": "true"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	check, err = p.Check(ctx, checkRequest(t, inputs))
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+	checked, err := plugin.UnmarshalProperties(check.GetInputs(), plugin.MarshalOptions{})
+	if assert.NoError(t, err) {
+		assert.True(t, checked["skipAwait"].BoolValue())
+	}
+}
+
+func TestSecretInputs(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient()
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{"service": "echo"})
+	inputs["image"] = resource.MakeSecret(resource.NewStringProperty("registry.test/echo:latest"))
+	s, err := plugin.MarshalProperties(inputs, plugin.MarshalOptions{SkipNulls: true, KeepSecrets: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	check, err := p.Check(ctx, &pulumirpc.CheckRequest{Urn: testFunctionURN, News: s})
+	if !assert.NoError(t, err) || !assert.Empty(t, check.GetFailures()) {
+		return
+	}
+
+	created, err := p.Create(ctx, &pulumirpc.CreateRequest{Urn: testFunctionURN, Properties: check.GetInputs()})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if functions := faas.Functions(); assert.Len(t, functions, 1) {
+		assert.Equal(t, "registry.test/echo:latest", functions[0].Image)
+	}
+
+	outputs, err := plugin.UnmarshalProperties(created.GetProperties(), plugin.MarshalOptions{KeepSecrets: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, outputs["image"].IsSecret())
+	assert.True(t, outputs["customResource"].IsSecret())
+	assert.False(t, outputs["service"].IsSecret())
+
+	// Refreshing the function must not reveal the secret.
+	read, err := p.Read(ctx, &pulumirpc.ReadRequest{
+		Id: created.GetId(), Urn: testFunctionURN, Properties: created.GetProperties(), Inputs: check.GetInputs(),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	refreshed, err := plugin.UnmarshalProperties(read.GetProperties(), plugin.MarshalOptions{KeepSecrets: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, refreshed["image"].IsSecret())
+	assert.True(t, refreshed["customResource"].IsSecret())
+	assert.False(t, refreshed["service"].IsSecret())
+}
+
+func TestGetFunction(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient(client.Function{
+		Service: "echo", Namespace: "staging", Image: "ghcr.io/openfaas/alpine:latest",
+		Labels: map[string]string{"team": "platform"},
+	})
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	invoke := func(args map[string]interface{}) (*pulumirpc.InvokeResponse, error) {
+		s, err := plugin.MarshalProperties(resource.NewPropertyMapFromMap(args), plugin.MarshalOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return p.Invoke(ctx, &pulumirpc.InvokeRequest{Tok: getFunctionToken, Args: s})
+	}
+
+	resp, err := invoke(map[string]interface{}{"service": "echo", "namespace": "staging"})
+	if !assert.NoError(t, err) || !assert.Empty(t, resp.GetFailures()) {
+		return
+	}
+	result, err := plugin.UnmarshalProperties(resp.GetReturn(), plugin.MarshalOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ghcr.io/openfaas/alpine:latest", result["image"].StringValue())
+	assert.Equal(t, "staging", result["namespace"].StringValue())
+	assert.Equal(t, "platform", result["labels"].ObjectValue()["team"].StringValue())
+	assert.Equal(t, "http://gateway.test:8080/function/echo.staging", result["url"].StringValue())
+
+	_, err = invoke(map[string]interface{}{"service": "missing"})
+	assert.Error(t, err)
+
+	resp, err = invoke(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Len(t, resp.GetFailures(), 1)
+}
+
+func TestListFunctions(t *testing.T) {
+	ctx := context.Background()
+	faas := fake.NewClient(
+		client.Function{Service: "resize", Image: "resize", Labels: map[string]string{"team": "media"}},
+		client.Function{Service: "echo", Image: "echo", Labels: map[string]string{"team": "platform"}},
+		client.Function{Service: "crop", Image: "crop", Labels: map[string]string{"team": "media"}},
+		client.Function{Service: "echo", Namespace: "staging", Image: "echo"},
+	)
+	p, err := newTestProvider(faas, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	list := func(args map[string]interface{}) []string {
+		s, err := plugin.MarshalProperties(resource.NewPropertyMapFromMap(args), plugin.MarshalOptions{})
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		resp, err := p.Invoke(ctx, &pulumirpc.InvokeRequest{Tok: listFunctionsToken, Args: s})
+		if !assert.NoError(t, err) || !assert.Empty(t, resp.GetFailures()) {
+			return nil
+		}
+		result, err := plugin.UnmarshalProperties(resp.GetReturn(), plugin.MarshalOptions{})
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var urls []string
+		for _, f := range result["functions"].ArrayValue() {
+			urls = append(urls, f.ObjectValue()["url"].StringValue())
+		}
+		return urls
+	}
+
+	assert.Equal(t, []string{
+		"http://gateway.test:8080/function/crop",
+		"http://gateway.test:8080/function/echo",
+		"http://gateway.test:8080/function/resize",
+	}, list(map[string]interface{}{}))
+	assert.Equal(t, []string{
+		"http://gateway.test:8080/function/crop",
+		"http://gateway.test:8080/function/resize",
+	}, list(map[string]interface{}{"labels": map[string]interface{}{"team": "media"}}))
+	assert.Equal(t, []string{
+		"http://gateway.test:8080/function/echo.staging",
+	}, list(map[string]interface{}{"namespace": "staging"}))
+}
@@ -0,0 +1,60 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+)
+
+// gatewayCall performs a single logical gateway operation on behalf of a resource operation. The operation waits for
+// a free gateway slot if the provider limits the number of concurrent gateway calls. The gateway clients retry
+// idempotent requests that fail due to a transient error and enforce the provider's rate limit, if any.
+func (p *faasProvider) gatewayCall(ctx context.Context, op func() error) error {
+	if p.gatewaySlots != nil {
+		select {
+		case p.gatewaySlots <- struct{}{}:
+			defer func() { <-p.gatewaySlots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return op()
+}
+
+// withRetries calls the given non-idempotent gateway operation, retrying it according to the given policy if it fails
+// due to a transient error. The operation must tolerate being repeated after an attempt that took effect. Retries stop
+// once the context is done.
+func withRetries(ctx context.Context, label string, policy client.RetryPolicy, op func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || !client.IsTransient(err) || attempt == policy.MaxRetries {
+			return err
+		}
+
+		delay := policy.Backoff(attempt)
+		glog.V(3).Infof("%s: transient gateway error, retrying in %v: %v", label, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// typeSpec is the type of a property in a Pulumi package schema.
+type typeSpec struct {
+	Type                 string    `json:"type,omitempty"`
+	Ref                  string    `json:"$ref,omitempty"`
+	Items                *typeSpec `json:"items,omitempty"`
+	AdditionalProperties *typeSpec `json:"additionalProperties,omitempty"`
+}
+
+// propertySpec describes a property in a Pulumi package schema.
+type propertySpec struct {
+	typeSpec
+	Description        string      `json:"description,omitempty"`
+	Default            interface{} `json:"default,omitempty"`
+	DeprecationMessage string      `json:"deprecationMessage,omitempty"`
+	Secret             bool        `json:"secret,omitempty"`
+}
+
+// objectTypeSpec describes an object type in a Pulumi package schema.
+type objectTypeSpec struct {
+	Type       string                  `json:"type"`
+	Properties map[string]propertySpec `json:"properties,omitempty"`
+	Required   []string                `json:"required,omitempty"`
+}
+
+// resourceSpec describes a resource in a Pulumi package schema.
+type resourceSpec struct {
+	objectTypeSpec
+	InputProperties map[string]propertySpec `json:"inputProperties,omitempty"`
+	RequiredInputs  []string                `json:"requiredInputs,omitempty"`
+}
+
+// functionSpec describes a function in a Pulumi package schema.
+type functionSpec struct {
+	Inputs  *objectTypeSpec `json:"inputs,omitempty"`
+	Outputs *objectTypeSpec `json:"outputs,omitempty"`
+}
+
+// configSpec describes the configuration variables of a Pulumi package.
+type configSpec struct {
+	Variables map[string]propertySpec `json:"variables,omitempty"`
+	Defaults  []string                `json:"defaults,omitempty"`
+}
+
+// packageSpec is a Pulumi package schema.
+type packageSpec struct {
+	Name      string                    `json:"name"`
+	Version   string                    `json:"version,omitempty"`
+	Config    configSpec                `json:"config"`
+	Provider  resourceSpec              `json:"provider"`
+	Resources map[string]resourceSpec   `json:"resources,omitempty"`
+	Functions map[string]functionSpec   `json:"functions,omitempty"`
+	Types     map[string]objectTypeSpec `json:"types,omitempty"`
+}
+
+// resourceSchemas maps the tokens of the provider's resources to their schemas.
+var resourceSchemas = map[string]interface{}{
+	functionType: function{},
+}
+
+// propertyDescriptions maps schema types to the descriptions of their properties. The configuration keys are
+// described as sentence fragments for use in error messages, so they are converted to sentences for the schema. A
+// gateway's properties have the same meanings as the configuration keys of the same names.
+var propertyDescriptions = map[reflect.Type]map[string]string{
+	reflect.TypeOf(providerConfig{}):    sentences(configDescriptions),
+	reflect.TypeOf(gateway{}):           sentences(configDescriptions),
+	reflect.TypeOf(function{}):          functionDescriptions,
+	reflect.TypeOf(gatewayArgs{}):       gatewayArgsDescriptions,
+	reflect.TypeOf(gatewayHealth{}):     gatewayHealthDescriptions,
+	reflect.TypeOf(getFunctionArgs{}):   getFunctionArgsDescriptions,
+	reflect.TypeOf(functionInfo{}):      functionInfoDescriptions,
+	reflect.TypeOf(listFunctionsArgs{}): listFunctionsArgsDescriptions,
+	reflect.TypeOf(functionList{}):      functionListDescriptions,
+}
+
+// sentences returns a copy of the given descriptions with each converted from a sentence fragment to a sentence.
+func sentences(descriptions map[string]string) map[string]string {
+	result := make(map[string]string, len(descriptions))
+	for name, d := range descriptions {
+		runes := []rune(d)
+		runes[0] = unicode.ToUpper(runes[0])
+		result[name] = string(runes) + "."
+	}
+	return result
+}
+
+// schemaGenerator builds a package schema from the provider's tagged Go types. Struct types that are referenced by
+// properties are collected into types as they are encountered.
+type schemaGenerator struct {
+	pkg   string
+	types map[string]objectTypeSpec
+}
+
+// PackageSchema returns the Pulumi package schema for the provider, as JSON. The schema is generated from the same
+// Go types that the provider uses to check and decode properties, so it cannot drift from the provider's behavior.
+func PackageSchema(name, version string) ([]byte, error) {
+	g := &schemaGenerator{pkg: name, types: map[string]objectTypeSpec{}}
+	spec := packageSpec{
+		Name:      name,
+		Version:   version,
+		Resources: map[string]resourceSpec{},
+		Functions: map[string]functionSpec{},
+	}
+
+	config, err := g.objectType(reflect.TypeOf(providerConfig{}))
+	if err != nil {
+		return nil, errors.Wrap(err, "config")
+	}
+	for name, prop := range config.Properties {
+		prop.Secret = prop.Secret || secretConfigKeys[name]
+		config.Properties[name] = prop
+	}
+	spec.Config = configSpec{Variables: config.Properties, Defaults: config.Required}
+	spec.Provider = resourceSpec{
+		objectTypeSpec:  objectTypeSpec{Type: "object"},
+		InputProperties: config.Properties,
+		RequiredInputs:  config.Required,
+	}
+
+	for token, schema := range resourceSchemas {
+		r, err := g.resource(reflect.TypeOf(schema))
+		if err != nil {
+			return nil, errors.Wrap(err, token)
+		}
+		spec.Resources[token] = r
+	}
+
+	for token, schema := range invokeSchemas {
+		inputs, err := g.objectType(reflect.TypeOf(schema.args))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%v inputs", token)
+		}
+		outputs, err := g.objectType(reflect.TypeOf(schema.result))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%v outputs", token)
+		}
+		spec.Functions[token] = functionSpec{Inputs: &inputs, Outputs: &outputs}
+	}
+
+	spec.Types = g.types
+	return json.MarshalIndent(spec, "", "    ")
+}
+
+// resource returns the schema of a resource with the given schema type. Every property is an input, and the outputs
+// that are always present are those that are required or computed.
+func (g *schemaGenerator) resource(t reflect.Type) (resourceSpec, error) {
+	obj, err := g.objectType(t)
+	if err != nil {
+		return resourceSpec{}, err
+	}
+	fields, err := structFields(t)
+	if err != nil {
+		return resourceSpec{}, err
+	}
+	r := resourceSpec{
+		objectTypeSpec:  objectTypeSpec{Type: "object", Properties: obj.Properties},
+		InputProperties: obj.Properties,
+		RequiredInputs:  obj.Required,
+	}
+	for _, f := range fields {
+		if !f.desc.optional || f.desc.computed {
+			r.Required = append(r.Required, f.desc.name)
+		}
+	}
+	return r, nil
+}
+
+// objectType returns the schema of the object type described by the given struct type.
+func (g *schemaGenerator) objectType(t reflect.Type) (objectTypeSpec, error) {
+	fields, err := structFields(t)
+	if err != nil {
+		return objectTypeSpec{}, err
+	}
+	obj := objectTypeSpec{Type: "object", Properties: map[string]propertySpec{}}
+	for _, f := range fields {
+		prop, err := g.property(f)
+		if err != nil {
+			return objectTypeSpec{}, errors.Wrap(err, f.desc.name)
+		}
+		prop.Description = propertyDescriptions[t][f.desc.name]
+		obj.Properties[f.desc.name] = prop
+		if !f.desc.optional {
+			obj.Required = append(obj.Required, f.desc.name)
+		}
+	}
+	return obj, nil
+}
+
+// property returns the schema of the given struct field.
+func (g *schemaGenerator) property(f structField) (propertySpec, error) {
+	t, err := g.typeOf(f.typ)
+	if err != nil {
+		return propertySpec{}, err
+	}
+	prop := propertySpec{typeSpec: t, DeprecationMessage: f.desc.deprecated, Secret: f.desc.secret}
+	if f.desc.defaultValue != nil {
+		prop.Default = configValue(*f.desc.defaultValue, f.typ).Mappable()
+	}
+	return prop, nil
+}
+
+// typeOf returns the schema type of values of the given Go type. Struct types are added to the package's types and
+// referenced by token.
+func (g *schemaGenerator) typeOf(t reflect.Type) (typeSpec, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType || isBytes(t):
+		return typeSpec{Type: "string"}, nil
+	case t == assetType:
+		return typeSpec{Ref: "pulumi.json#/Asset"}, nil
+	case t == archiveType:
+		return typeSpec{Ref: "pulumi.json#/Archive"}, nil
+	case t == rawMessageType || isUnmarshaler(t):
+		return typeSpec{Ref: "pulumi.json#/Any"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return typeSpec{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typeSpec{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return typeSpec{Type: "number"}, nil
+	case reflect.String:
+		return typeSpec{Type: "string"}, nil
+	case reflect.Interface:
+		return typeSpec{Ref: "pulumi.json#/Any"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeOf(t.Elem())
+		if err != nil {
+			return typeSpec{}, err
+		}
+		return typeSpec{Type: "array", Items: &items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return typeSpec{}, errors.Errorf("unsupported map key type %v", t.Key())
+		}
+		elem, err := g.typeOf(t.Elem())
+		if err != nil {
+			return typeSpec{}, err
+		}
+		return typeSpec{Type: "object", AdditionalProperties: &elem}, nil
+	case reflect.Struct:
+		token := g.pkg + ":index:" + exportedName(t.Name())
+		if _, ok := g.types[token]; !ok {
+			// Reserve the token before generating the type so that recursive types terminate.
+			g.types[token] = objectTypeSpec{}
+			obj, err := g.objectType(t)
+			if err != nil {
+				delete(g.types, token)
+				return typeSpec{}, err
+			}
+			g.types[token] = obj
+		}
+		return typeSpec{Ref: "#/types/" + token}, nil
+	default:
+		return typeSpec{}, errors.Errorf("unsupported type %v", t)
+	}
+}
+
+// exportedName returns the given Go type name with its first letter in upper case.
+func exportedName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
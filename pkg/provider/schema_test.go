@@ -0,0 +1,244 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageSchema(t *testing.T) {
+	b, err := PackageSchema("openfaas", "0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var spec packageSpec
+	if !assert.NoError(t, json.Unmarshal(b, &spec)) {
+		return
+	}
+
+	assert.Equal(t, []string{"endpoint"}, spec.Config.Defaults)
+	assert.True(t, spec.Config.Variables["password"].Secret)
+	assert.True(t, spec.Config.Variables["headers"].Secret)
+	assert.Equal(t, "integer", spec.Config.Variables["maxRetries"].Type)
+	assert.Equal(t, "#/types/openfaas:index:Gateway", spec.Config.Variables["gateways"].AdditionalProperties.Ref)
+
+	fn, ok := spec.Resources[functionType]
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, []string{"service", "image"}, fn.RequiredInputs)
+	assert.Contains(t, fn.Required, "replicas")
+	assert.True(t, fn.InputProperties["registryAuth"].Secret)
+	assert.Equal(t, "array", fn.InputProperties["secrets"].Type)
+	assert.Equal(t, "string", fn.InputProperties["secrets"].Items.Type)
+	assert.Equal(t, "string", fn.InputProperties["envVars"].AdditionalProperties.Type)
+	assert.Equal(t, "#/types/openfaas:index:Gateway", fn.InputProperties["gateway"].Ref)
+
+	gw, ok := spec.Types["openfaas:index:Gateway"]
+	if assert.True(t, ok) {
+		assert.Equal(t, []string{"endpoint"}, gw.Required)
+		assert.True(t, gw.Properties["password"].Secret)
+		assert.True(t, gw.Properties["headers"].Secret)
+		assert.False(t, gw.Properties["username"].Secret)
+	}
+
+	health, ok := spec.Functions[getGatewayHealthToken]
+	if assert.True(t, ok) {
+		assert.Equal(t, []string{"healthy"}, health.Outputs.Required)
+		assert.Contains(t, health.Inputs.Properties, "gatewayProfile")
+	}
+}
+
+func TestSchemaDescriptions(t *testing.T) {
+	b, err := PackageSchema("openfaas", "0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var spec packageSpec
+	if !assert.NoError(t, json.Unmarshal(b, &spec)) {
+		return
+	}
+
+	assertDescribed := func(owner string, props map[string]propertySpec) {
+		for name, prop := range props {
+			assert.NotEmpty(t, prop.Description, "%v.%v has no description", owner, name)
+		}
+	}
+	assertDescribed("config", spec.Config.Variables)
+	for token, r := range spec.Resources {
+		assertDescribed(token, r.InputProperties)
+	}
+	for token, f := range spec.Functions {
+		assertDescribed(token, f.Inputs.Properties)
+		assertDescribed(token, f.Outputs.Properties)
+	}
+	for token, typ := range spec.Types {
+		assertDescribed(token, typ.Properties)
+	}
+
+	assert.Equal(t, "The endpoint of the OpenFaaS API gateway, including any path under which it is served.",
+		spec.Config.Variables["endpoint"].Description)
+}
+
+// nodeSDKDir is the directory of the Node SDK, whose modules are written by hand.
+const nodeSDKDir = "../../sdk/nodejs"
+
+// nodeSDKTypes maps the tokens of the schema's types to the names of the Node SDK interfaces that declare them.
+var nodeSDKTypes = map[string]string{
+	"openfaas:index:Gateway":      "FunctionGateway",
+	"openfaas:index:FunctionInfo": "GetFunctionResult",
+}
+
+var (
+	tsInterfaceRe = regexp.MustCompile(`(?ms)^export interface (\w+) \{\n(.*?)^\}`)
+	tsPropertyRe  = regexp.MustCompile(`(?m)^\s+readonly (\w+)(\??): (.+);$`)
+	tsInvokeRe    = regexp.MustCompile(`(?s)runtime\.invoke\("([^"]+)", \{\n(.*?)\}, opts\)`)
+	tsInvokeArgRe = regexp.MustCompile(`"(\w+)": args\.(\w+),`)
+)
+
+// readNodeSDK returns the source of each of the Node SDK's modules, keyed by file name.
+func readNodeSDK(t *testing.T) map[string]string {
+	paths, err := filepath.Glob(filepath.Join(nodeSDKDir, "*.ts"))
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, paths) {
+		return nil
+	}
+	modules := map[string]string{}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if assert.NoError(t, err) {
+			modules[filepath.Base(path)] = string(b)
+		}
+	}
+	return modules
+}
+
+// unwrapInputs strips the pulumi.Input wrappers from the given TypeScript type, which input types use to accept
+// values that are not yet known.
+func unwrapInputs(typ string) string {
+	const wrapper = "pulumi.Input<"
+	for {
+		start := strings.Index(typ, wrapper)
+		if start == -1 {
+			return typ
+		}
+		depth, end := 1, start+len(wrapper)
+		for ; depth > 0 && end < len(typ); end++ {
+			switch typ[end] {
+			case '<':
+				depth++
+			case '>':
+				depth--
+			}
+		}
+		typ = typ[:start] + typ[start+len(wrapper):end-1] + typ[end:]
+	}
+}
+
+// tsType returns the TypeScript type with which the Node SDK declares a property of the given schema type.
+func tsType(t typeSpec) string {
+	switch {
+	case t.Ref != "":
+		return nodeSDKTypes[strings.TrimPrefix(t.Ref, "#/types/")]
+	case t.Type == "array":
+		return tsType(*t.Items) + "[]"
+	case t.Type == "object" && t.AdditionalProperties != nil:
+		return "{[key: string]: " + tsType(*t.AdditionalProperties) + "}"
+	case t.Type == "integer":
+		return "number"
+	default:
+		return t.Type
+	}
+}
+
+// tsProperties returns the TypeScript types of the given object type's properties. Optional properties are prefixed
+// with "?".
+func tsProperties(obj objectTypeSpec) map[string]string {
+	required := map[string]bool{}
+	for _, name := range obj.Required {
+		required[name] = true
+	}
+	props := map[string]string{}
+	for name, prop := range obj.Properties {
+		typ := tsType(prop.typeSpec)
+		if !required[name] {
+			typ = "?" + typ
+		}
+		props[name] = typ
+	}
+	return props
+}
+
+// TestNodeSDKMatchesSchema checks that the Node SDK's hand-written invokes and types agree with the package schema,
+// so that a change to the provider's Go types that is not mirrored in the SDK fails the build.
+func TestNodeSDKMatchesSchema(t *testing.T) {
+	b, err := PackageSchema("openfaas", "0.0.1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var spec packageSpec
+	if !assert.NoError(t, json.Unmarshal(b, &spec)) {
+		return
+	}
+
+	modules := readNodeSDK(t)
+	interfaces := map[string]map[string]string{}
+	invokes := map[string][]string{}
+	for _, src := range modules {
+		for _, m := range tsInterfaceRe.FindAllStringSubmatch(src, -1) {
+			props := map[string]string{}
+			for _, p := range tsPropertyRe.FindAllStringSubmatch(m[2], -1) {
+				props[p[1]] = p[2] + unwrapInputs(p[3])
+			}
+			interfaces[m[1]] = props
+		}
+		for _, m := range tsInvokeRe.FindAllStringSubmatch(src, -1) {
+			for _, arg := range tsInvokeArgRe.FindAllStringSubmatch(m[2], -1) {
+				assert.Equal(t, arg[1], arg[2], "%v passes args.%v as %v", m[1], arg[2], arg[1])
+				invokes[m[1]] = append(invokes[m[1]], arg[1])
+			}
+		}
+	}
+
+	for token, typ := range spec.Types {
+		name, ok := nodeSDKTypes[token]
+		if assert.True(t, ok, "the Node SDK does not declare %v", token) {
+			assert.Equal(t, tsProperties(typ), interfaces[name], "%v (%v)", name, token)
+		}
+	}
+
+	for token, fn := range spec.Functions {
+		name := token[strings.LastIndex(token, ":")+1:]
+		if !assert.Contains(t, modules, name+".ts", "the Node SDK does not implement %v", token) {
+			continue
+		}
+		title := strings.ToUpper(name[:1]) + name[1:]
+		assert.Equal(t, tsProperties(*fn.Inputs), interfaces[title+"Args"], "%vArgs", title)
+		assert.Equal(t, tsProperties(*fn.Outputs), interfaces[title+"Result"], "%vResult", title)
+
+		var inputs []string
+		for input := range fn.Inputs.Properties {
+			inputs = append(inputs, input)
+		}
+		assert.ElementsMatch(t, inputs, invokes[token], "arguments passed to %v", token)
+	}
+	assert.Len(t, invokes, len(spec.Functions), "the Node SDK invokes functions that the schema does not declare")
+}
@@ -15,17 +15,33 @@
 package provider
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/pulumi/pulumi/pkg/resource/provider"
 	"github.com/pulumi/pulumi/pkg/util/cmdutil"
 	lumirpc "github.com/pulumi/pulumi/sdk/proto/go"
 )
 
-// Serve launches the gRPC server for the Pulumi Kubernetes resource provider.
+// Serve launches the gRPC server for the Pulumi OpenFaaS resource provider. If the provider is run with -get-schema,
+// it prints its package schema to stdout instead. The engine that the provider is built against predates the
+// GetSchema RPC, so this is how tools obtain the schema from the plugin.
 func Serve(providerName, version string) {
+	for _, arg := range os.Args[1:] {
+		if arg == "-get-schema" || arg == "--get-schema" {
+			schema, err := PackageSchema(providerName, version)
+			if err != nil {
+				cmdutil.ExitError(err.Error())
+			}
+			fmt.Println(string(schema))
+			return
+		}
+	}
+
 	// Start gRPC service.
 	err := provider.Main(
 		providerName, func(host *provider.HostClient) (lumirpc.ResourceProviderServer, error) {
-			return makeFaasProvider(providerName, version)
+			return makeFaasProvider(host, providerName, version)
 		})
 
 	if err != nil {
@@ -0,0 +1,66 @@
+// Copyright 2016-2018, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"net/http"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+
+	"github.com/pulumi/pulumi-openfaas/pkg/client"
+)
+
+// gatewayTracer records an OpenTracing span for each gateway request. The plugin host installs the engine's tracer
+// as the global tracer when the engine is run with --tracing, so gateway requests appear in the same traces as the
+// resource operations that issue them. Otherwise, the global tracer is a no-op.
+type gatewayTracer struct{}
+
+func (gatewayTracer) StartSpan(ctx context.Context, req client.RequestSpan,
+	header http.Header) (context.Context, func(int, error)) {
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "openfaas "+req.Method+" "+req.Route, ext.SpanKindRPCClient)
+	ext.HTTPMethod.Set(span, req.Method)
+	ext.PeerHostname.Set(span, req.Host)
+	if req.Function != "" {
+		span.SetTag("faas.function", req.Function)
+	}
+
+	// A span whose context cannot be propagated is still recorded; it is simply not linked to the gateway's spans.
+	contract.IgnoreError(span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(header)))
+
+	return ctx, func(statusCode int, err error) {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(log.Error(err))
+		} else {
+			ext.HTTPStatusCode.Set(span, uint16(statusCode))
+			ext.Error.Set(span, statusCode >= http.StatusBadRequest)
+		}
+		span.Finish()
+	}
+}
+
+// withSpan returns a copy of the given context that carries the tracing span from spanCtx, if any.
+func withSpan(ctx, spanCtx context.Context) context.Context {
+	if span := opentracing.SpanFromContext(spanCtx); span != nil {
+		return opentracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
@@ -3,21 +3,220 @@ import * as pulumi from "@pulumi/pulumi";
 let __config = new pulumi.Config("openfaas");
 
 /**
- * The URL of the OpenFaaS API gateway.
+ * The URL of the OpenFaaS API gateway, including any path under which it is served, e.g. https://example.com/faas
+ * when the gateway is exposed by a reverse proxy. Defaults to the value of the OPENFAAS_URL environment variable.
  */
 export let endpoint = __config.get("endpoint");
 
 /**
- * The username (if any) to use when authenticating with the OpennFaaS API gateway.
+ * The username (if any) to use when authenticating with the OpennFaaS API gateway. Defaults to the value of the
+ * OPENFAAS_USERNAME environment variable.
  */
 export let username = __config.get("username");
 
 /**
- * The password (if any) to use when authenticating with the OpennFaaS API gateway.
+ * The password (if any) to use when authenticating with the OpennFaaS API gateway. Defaults to the value of the
+ * OPENFAAS_PASSWORD environment variable.
  */
-export let password = __config.get("password");
+export let password: pulumi.Output<string> | undefined = __config.getSecret("password");
+
+/**
+ * A bearer token to use when authenticating with the OpenFaaS API gateway, e.g. for gateways that are protected by an
+ * OIDC plugin or a reverse proxy. Cannot be combined with username. Defaults to the value of the OPENFAAS_TOKEN
+ * environment variable.
+ */
+export let token: pulumi.Output<string> | undefined = __config.getSecret("token");
+
+/**
+ * The URL of the OIDC issuer from which to obtain tokens for OpenFaaS IAM using the client credentials grant.
+ */
+export let oidcIssuer: string | undefined = __config.get("oidcIssuer");
+
+/**
+ * The ID of the OIDC client with which to obtain tokens for OpenFaaS IAM.
+ */
+export let oidcClientId: string | undefined = __config.get("oidcClientId");
+
+/**
+ * The secret of the OIDC client with which to obtain tokens for OpenFaaS IAM.
+ */
+export let oidcClientSecret: pulumi.Output<string> | undefined = __config.getSecret("oidcClientSecret");
+
+/**
+ * A pre-obtained OIDC token to exchange for OpenFaaS IAM tokens. If set, oidcIssuer, oidcClientId, and
+ * oidcClientSecret are ignored.
+ */
+export let oidcToken: pulumi.Output<string> | undefined = __config.getSecret("oidcToken");
 
 /**
  * Whether or not to disable TLS verification when connecting to the OpenFaaS API gateway. Defaults to false.
  */
 export let tlsSkipVerify = __config.get("tlsSkipVerify");
+
+/**
+ * A PEM-encoded CA certificate, or the path to a file that contains one, to use when verifying the OpenFaaS API
+ * gateway's TLS certificate. Useful for gateways that use self-signed certificates.
+ */
+export let caCert: string | undefined = __config.get("caCert");
+
+/**
+ * A PEM-encoded client certificate, or the path to a file that contains one, to present to the OpenFaaS API gateway.
+ * Required by gateways that are fronted by proxies that terminate mutual TLS. Must be set together with clientKey.
+ */
+export let clientCert: string | undefined = __config.get("clientCert");
+
+/**
+ * The PEM-encoded private key, or the path to a file that contains it, for clientCert.
+ */
+export let clientKey: pulumi.Output<string> | undefined = __config.getSecret("clientKey");
+
+/**
+ * The URL of the HTTP or HTTPS proxy to use when connecting to the OpenFaaS API gateway. Defaults to the proxy
+ * specified by the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables, if any.
+ */
+export let proxyUrl: string | undefined = __config.get("proxyUrl");
+
+/**
+ * Additional HTTP headers to send with every request to the OpenFaaS API gateway, e.g. the `CF-Access-Client-Id` and
+ * `CF-Access-Client-Secret` headers required by gateways behind Cloudflare Access.
+ */
+export let headers: pulumi.Output<{[key: string]: string}> | undefined =
+    __config.getSecretObject<{[key: string]: string}>("headers");
+
+/**
+ * A command that prints the credentials to use when authenticating with the OpenFaaS API gateway, e.g. a script that
+ * reads them from Vault or AWS SSM. The command must print either a bare token or a JSON object with `token` or
+ * `username` and `password` fields. The gateway's endpoint is passed in the OPENFAAS_URL environment variable.
+ * Credentials printed by the command take precedence over username, password, and token.
+ */
+export let credentialCommand: string | undefined = __config.get("credentialCommand");
+
+/**
+ * The arguments to pass to credentialCommand.
+ */
+export let credentialArgs: string[] | undefined = __config.getObject<string[]>("credentialArgs");
+
+/**
+ * Whether or not to re-resolve credentials before each request to the OpenFaaS API gateway rather than once when the
+ * provider is configured. Applies to credentials taken from the OPENFAAS_* environment variables and credentials
+ * printed by credentialCommand, so that deployments that outlive short-lived tokens pick up rotated credentials.
+ * Defaults to false.
+ */
+export let refreshCredentials: boolean | undefined = __config.getBoolean("refreshCredentials");
+
+/**
+ * Named OpenFaaS API gateways that functions may select with their gatewayProfile property in place of the configured
+ * gateway. Each gateway specifies an endpoint and the credentials and connection settings used to reach it.
+ */
+export let gateways: {[name: string]: {[key: string]: any}} | undefined =
+    __config.getObject<{[name: string]: {[key: string]: any}}>("gateways");
+
+/**
+ * The namespace to deploy functions to if a function does not specify one. Defaults to the gateway's namespace.
+ */
+export let namespace: string | undefined = __config.get("namespace");
+
+/**
+ * Labels to apply to every function managed by this provider. Labels specified by a function take precedence.
+ */
+export let defaultLabels: {[key: string]: string} | undefined = __config.getObject<{[key: string]: string}>("defaultLabels");
+
+/**
+ * Annotations to apply to every function managed by this provider. Annotations specified by a function take
+ * precedence.
+ */
+export let defaultAnnotations: {[key: string]: string} | undefined = __config.getObject<{[key: string]: string}>("defaultAnnotations");
+
+/**
+ * The maximum number of concurrent calls the provider makes to the OpenFaaS API gateway. Defaults to 0 (unlimited).
+ */
+export let parallelism: number | undefined = __config.getNumber("parallelism");
+
+/**
+ * The maximum number of times to retry calls to the OpenFaaS API gateway that fail with transient errors. Defaults to
+ * 5.
+ */
+export let maxRetries: number | undefined = __config.getNumber("maxRetries");
+
+/**
+ * The maximum average number of requests per second the provider sends to the OpenFaaS API gateway. Requests above
+ * this rate are queued rather than failed. Defaults to 0 (unlimited).
+ */
+export let requestsPerSecond: number | undefined = __config.getNumber("requestsPerSecond");
+
+/**
+ * The maximum number of requests the provider may send to the OpenFaaS API gateway in a burst above requestsPerSecond.
+ * Defaults to 1.
+ */
+export let burst: number | undefined = __config.getNumber("burst");
+
+/**
+ * Whether or not to skip checking that the OpenFaaS API gateway is reachable when the provider is configured. Defaults
+ * to false.
+ */
+export let skipHealthCheck: boolean | undefined = __config.getBoolean("skipHealthCheck");
+
+/**
+ * Whether or not the provider should avoid contacting the OpenFaaS API gateway. Useful for running `pulumi preview` in
+ * air-gapped environments that cannot reach the gateway: functions are assumed to be unchanged when refreshed, and
+ * deployments fail. Defaults to false.
+ */
+export let offline: boolean | undefined = __config.getBoolean("offline");
+
+/**
+ * A suffix to append to the `pulumi-openfaas/<version>` User-Agent header sent with each request to the OpenFaaS API
+ * gateway, e.g. to identify the team or pipeline that owns a stack.
+ */
+export let userAgentSuffix: string | undefined = __config.get("userAgentSuffix");
+
+/**
+ * The maximum number of idle connections to the OpenFaaS API gateway to keep open for reuse. Defaults to 100; 0 means
+ * unlimited.
+ */
+export let maxIdleConns: number | undefined = __config.getNumber("maxIdleConns");
+
+/**
+ * The maximum number of idle connections to keep open per gateway. Stacks with many functions may benefit from
+ * raising this to match parallelism. Defaults to 2.
+ */
+export let maxIdleConnsPerHost: number | undefined = __config.getNumber("maxIdleConnsPerHost");
+
+/**
+ * The time in seconds after which idle connections to the OpenFaaS API gateway are closed. Defaults to 90; 0 means
+ * never.
+ */
+export let idleConnTimeout: number | undefined = __config.getNumber("idleConnTimeout");
+
+/**
+ * Whether or not to use HTTP/2 when the OpenFaaS API gateway supports it. Defaults to true.
+ */
+export let http2: boolean | undefined = __config.getBoolean("http2");
+
+/**
+ * The maximum time in seconds to spend establishing a connection to the OpenFaaS API gateway, including the TLS
+ * handshake. Defaults to 0 (unlimited).
+ */
+export let connectTimeout: number | undefined = __config.getNumber("connectTimeout");
+
+/**
+ * The maximum time in seconds to spend on a single request to the OpenFaaS API gateway. Defaults to 0 (unlimited).
+ */
+export let requestTimeout: number | undefined = __config.getNumber("requestTimeout");
+
+/**
+ * The maximum time in seconds to spend on a resource operation if the resource does not specify a custom timeout.
+ * Defaults to 0 (unlimited).
+ */
+export let operationTimeout: number | undefined = __config.getNumber("operationTimeout");
+
+/**
+ * Whether or not to accept function properties whose names differ from the schema's only in case and separators, e.g.
+ * `env_vars` or `EnvVars` for `envVars`. Eases migration from stack.yml files. Defaults to false.
+ */
+export let lenientPropertyKeys: boolean | undefined = __config.getBoolean("lenientPropertyKeys");
+
+/**
+ * Whether or not to accept strings such as `"true"` or `"3"` for boolean and numeric function properties, e.g. from
+ * YAML programs or converted stack files. Each conversion is reported as a warning. Defaults to false.
+ */
+export let coerceStrings: boolean | undefined = __config.getBoolean("coerceStrings");
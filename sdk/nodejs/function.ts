@@ -17,6 +17,7 @@ export class Function extends pulumi.CustomResource {
     }
 
     public readonly service: pulumi.Output<string>;
+    public readonly namespace: pulumi.Output<string> | undefined;
     public readonly network: pulumi.Output<string> | undefined;
     public readonly image: pulumi.Output<string>;
     public readonly envProcess: pulumi.Output<string>;
@@ -24,6 +25,31 @@ export class Function extends pulumi.CustomResource {
     public readonly labels: pulumi.Output<{[key: string]: string}> | undefined;
     public readonly annotations: pulumi.Output<{[key: string]: string}> | undefined;
     public readonly registryAuth: pulumi.Output<string> | undefined;
+    public readonly gateway: pulumi.Output<FunctionGateway> | undefined;
+    public readonly gatewayProfile: pulumi.Output<string> | undefined;
+    public readonly skipAwait: pulumi.Output<boolean> | undefined;
+    public readonly deleteBeforeReplace: pulumi.Output<boolean> | undefined;
+    /**
+     * The number of replicas of this function that the gateway is trying to run.
+     */
+    public /*out*/ readonly replicas: pulumi.Output<number>;
+    /**
+     * The number of replicas of this function that are ready to serve requests.
+     */
+    public /*out*/ readonly availableReplicas: pulumi.Output<number>;
+    /**
+     * The number of times this function has been invoked.
+     */
+    public /*out*/ readonly invocationCount: pulumi.Output<number>;
+    /**
+     * The time at which this function was created, as an RFC 3339 timestamp, if reported by the gateway.
+     */
+    public /*out*/ readonly createdAt: pulumi.Output<string> | undefined;
+    /**
+     * This function rendered as an openfaas.com/v1 Function custom resource in YAML, for use with the OpenFaaS
+     * operator.
+     */
+    public /*out*/ readonly customResource: pulumi.Output<string>;
 
     /**
      * Create a Function resource with the given unique name, arguments, and options.
@@ -38,6 +64,7 @@ export class Function extends pulumi.CustomResource {
         if (opts && opts.id) {
             const state = argsOrState as FunctionState | undefined;
             inputs["service"] = state ? state.service : undefined;
+            inputs["namespace"] = state ? state.namespace : undefined;
             inputs["network"] = state ? state.network : undefined;
             inputs["image"] = state ? state.image : undefined;
             inputs["envProcess"] = state ? state.envProcess : undefined;
@@ -45,6 +72,15 @@ export class Function extends pulumi.CustomResource {
             inputs["labels"] = state ? state.labels : undefined;
             inputs["annotations"] = state ? state.annotations : undefined;
             inputs["registryAuth"] = state ? state.registryAuth : undefined;
+            inputs["gateway"] = state ? state.gateway : undefined;
+            inputs["gatewayProfile"] = state ? state.gatewayProfile : undefined;
+            inputs["skipAwait"] = state ? state.skipAwait : undefined;
+            inputs["deleteBeforeReplace"] = state ? state.deleteBeforeReplace : undefined;
+            inputs["replicas"] = state ? state.replicas : undefined;
+            inputs["availableReplicas"] = state ? state.availableReplicas : undefined;
+            inputs["invocationCount"] = state ? state.invocationCount : undefined;
+            inputs["createdAt"] = state ? state.createdAt : undefined;
+            inputs["customResource"] = state ? state.customResource : undefined;
         } else {
             const args = argsOrState as FunctionArgs | undefined;
             if (!args || args.service === undefined) {
@@ -54,6 +90,7 @@ export class Function extends pulumi.CustomResource {
                 throw new Error("Missing required property 'image'");
             }
             inputs["service"] = args ? args.service : undefined;
+            inputs["namespace"] = args ? args.namespace : undefined;
             inputs["network"] = args ? args.network : undefined;
             inputs["image"] = args ? args.image : undefined;
             inputs["envProcess"] = args ? args.envProcess : undefined;
@@ -61,8 +98,20 @@ export class Function extends pulumi.CustomResource {
             inputs["labels"] = args ? args.labels : undefined;
             inputs["annotations"] = args ? args.annotations : undefined;
             inputs["registryAuth"] = args ? args.registryAuth : undefined;
+            inputs["gateway"] = args ? args.gateway : undefined;
+            inputs["gatewayProfile"] = args ? args.gatewayProfile : undefined;
+            inputs["skipAwait"] = args ? args.skipAwait : undefined;
+            inputs["deleteBeforeReplace"] = args ? args.deleteBeforeReplace : undefined;
+            inputs["replicas"] = undefined /*out*/;
+            inputs["availableReplicas"] = undefined /*out*/;
+            inputs["invocationCount"] = undefined /*out*/;
+            inputs["createdAt"] = undefined /*out*/;
+            inputs["customResource"] = undefined /*out*/;
         }
-        super("openfaas:system:Function", name, inputs, opts);
+        // Functions were previously registered as openfaas:system:Function. Alias the old type so that existing stacks
+        // are upgraded in place rather than replacing their functions.
+        opts = pulumi.mergeOptions(opts, { aliases: [{ type: "openfaas:system:Function" }] });
+        super("openfaas:index:Function", name, inputs, opts);
     }
 }
 
@@ -71,6 +120,7 @@ export class Function extends pulumi.CustomResource {
  */
 export interface FunctionState {
     readonly service?: pulumi.Input<string>;
+    readonly namespace?: pulumi.Input<string>;
     readonly network?: pulumi.Input<string>;
     readonly image?: pulumi.Input<string>;
     readonly envProcess?: pulumi.Input<string>;
@@ -78,6 +128,46 @@ export interface FunctionState {
     readonly labels?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
     readonly annotations?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
     readonly registryAuth?: pulumi.Input<string>;
+    /**
+     * The OpenFaaS gateway to deploy this function to. Overrides the provider's configured gateway.
+     */
+    readonly gateway?: pulumi.Input<FunctionGateway>;
+    /**
+     * The name of the provider's gateway profile to deploy this function to. Overrides the provider's configured
+     * gateway. Cannot be combined with gateway.
+     */
+    readonly gatewayProfile?: pulumi.Input<string>;
+    /**
+     * Whether to skip waiting for this function to become ready after it is created. When a function is replaced,
+     * the function it replaces is not deleted until the new function is ready unless this is set.
+     */
+    readonly skipAwait?: pulumi.Input<boolean>;
+    /**
+     * Whether to delete this function before creating its replacement. By default, the function is deleted first
+     * only if its replacement has the same service name and namespace.
+     */
+    readonly deleteBeforeReplace?: pulumi.Input<boolean>;
+    /**
+     * The number of replicas of this function that the gateway is trying to run.
+     */
+    readonly replicas?: pulumi.Input<number>;
+    /**
+     * The number of replicas of this function that are ready to serve requests.
+     */
+    readonly availableReplicas?: pulumi.Input<number>;
+    /**
+     * The number of times this function has been invoked.
+     */
+    readonly invocationCount?: pulumi.Input<number>;
+    /**
+     * The time at which this function was created, as an RFC 3339 timestamp, if reported by the gateway.
+     */
+    readonly createdAt?: pulumi.Input<string>;
+    /**
+     * This function rendered as an openfaas.com/v1 Function custom resource in YAML, for use with the OpenFaaS
+     * operator.
+     */
+    readonly customResource?: pulumi.Input<string>;
 }
 
 /**
@@ -85,6 +175,7 @@ export interface FunctionState {
  */
 export interface FunctionArgs {
     readonly service: pulumi.Input<string>;
+    readonly namespace?: pulumi.Input<string>;
     readonly network?: pulumi.Input<string>;
     readonly image: pulumi.Input<string>;
     readonly envProcess?: pulumi.Input<string>;
@@ -92,4 +183,45 @@ export interface FunctionArgs {
     readonly labels?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
     readonly annotations?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
     readonly registryAuth?: pulumi.Input<string>;
+    /**
+     * The OpenFaaS gateway to deploy this function to. Overrides the provider's configured gateway.
+     */
+    readonly gateway?: pulumi.Input<FunctionGateway>;
+    /**
+     * The name of the provider's gateway profile to deploy this function to. Overrides the provider's configured
+     * gateway. Cannot be combined with gateway.
+     */
+    readonly gatewayProfile?: pulumi.Input<string>;
+    /**
+     * Whether to skip waiting for this function to become ready after it is created. When a function is replaced,
+     * the function it replaces is not deleted until the new function is ready unless this is set.
+     */
+    readonly skipAwait?: pulumi.Input<boolean>;
+    /**
+     * Whether to delete this function before creating its replacement. By default, the function is deleted first
+     * only if its replacement has the same service name and namespace.
+     */
+    readonly deleteBeforeReplace?: pulumi.Input<boolean>;
+}
+
+/**
+ * An OpenFaaS gateway that a function is deployed to in place of the provider's configured gateway.
+ */
+export interface FunctionGateway {
+    readonly endpoint: pulumi.Input<string>;
+    readonly username?: pulumi.Input<string>;
+    readonly password?: pulumi.Input<string>;
+    readonly token?: pulumi.Input<string>;
+    readonly oidcIssuer?: pulumi.Input<string>;
+    readonly oidcClientId?: pulumi.Input<string>;
+    readonly oidcClientSecret?: pulumi.Input<string>;
+    readonly oidcToken?: pulumi.Input<string>;
+    readonly tlsSkipVerify?: pulumi.Input<boolean>;
+    readonly caCert?: pulumi.Input<string>;
+    readonly clientCert?: pulumi.Input<string>;
+    readonly clientKey?: pulumi.Input<string>;
+    readonly proxyUrl?: pulumi.Input<string>;
+    readonly headers?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
+    readonly credentialCommand?: pulumi.Input<string>;
+    readonly credentialArgs?: pulumi.Input<pulumi.Input<string>[]>;
 }
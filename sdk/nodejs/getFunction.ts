@@ -0,0 +1,99 @@
+import * as pulumi from "@pulumi/pulumi";
+import { FunctionGateway } from "./function";
+
+/**
+ * Fetches the specification and status of an OpenFaaS function, which need not be managed by this program.
+ */
+export function getFunction(args: GetFunctionArgs, opts?: pulumi.InvokeOptions): Promise<GetFunctionResult> {
+    return pulumi.runtime.invoke("openfaas:index:getFunction", {
+        "service": args.service,
+        "namespace": args.namespace,
+        "gateway": args.gateway,
+        "gatewayProfile": args.gatewayProfile,
+    }, opts);
+}
+
+/**
+ * A collection of arguments for invoking getFunction.
+ */
+export interface GetFunctionArgs {
+    /**
+     * The name of the function to fetch.
+     */
+    readonly service: string;
+    /**
+     * The namespace of the function to fetch. Defaults to the provider's configured namespace.
+     */
+    readonly namespace?: string;
+    /**
+     * The OpenFaaS gateway to target in place of the provider's configured gateway.
+     */
+    readonly gateway?: FunctionGateway;
+    /**
+     * The name of the provider's gateway profile to target in place of the provider's configured gateway. Cannot be
+     * combined with gateway.
+     */
+    readonly gatewayProfile?: string;
+}
+
+/**
+ * A collection of values returned by getFunction.
+ */
+export interface GetFunctionResult {
+    /**
+     * The name of the function.
+     */
+    readonly service: string;
+    /**
+     * The namespace of the function, if the gateway reports one.
+     */
+    readonly namespace?: string;
+    /**
+     * The container image that implements the function.
+     */
+    readonly image: string;
+    /**
+     * The network that the function is attached to.
+     */
+    readonly network?: string;
+    /**
+     * The process that the function's watchdog runs to handle each request.
+     */
+    readonly envProcess?: string;
+    /**
+     * The environment variables set in the function's container.
+     */
+    readonly envVars?: {[key: string]: string};
+    /**
+     * The labels applied to the function.
+     */
+    readonly labels?: {[key: string]: string};
+    /**
+     * The annotations applied to the function.
+     */
+    readonly annotations?: {[key: string]: string};
+    /**
+     * The names of the secrets mounted into the function's container.
+     */
+    readonly secrets?: string[];
+    /**
+     * The number of replicas of the function that the gateway is trying to run.
+     */
+    readonly replicas: number;
+    /**
+     * The number of replicas of the function that are ready to serve requests.
+     */
+    readonly availableReplicas: number;
+    /**
+     * The number of times the function has been invoked.
+     */
+    readonly invocationCount: number;
+    /**
+     * The time at which the function was created, as an RFC 3339 timestamp, if reported.
+     */
+    readonly createdAt?: string;
+    /**
+     * The URL at which the function is invoked through the gateway.
+     */
+    readonly url: string;
+}
@@ -0,0 +1,42 @@
+import * as pulumi from "@pulumi/pulumi";
+import { FunctionGateway } from "./function";
+
+/**
+ * Checks the health of an OpenFaaS gateway. By default, the provider's configured gateway is checked.
+ */
+export function getGatewayHealth(args?: GetGatewayHealthArgs, opts?: pulumi.InvokeOptions): Promise<GetGatewayHealthResult> {
+    args = args || {};
+    return pulumi.runtime.invoke("openfaas:index:getGatewayHealth", {
+        "gateway": args.gateway,
+        "gatewayProfile": args.gatewayProfile,
+    }, opts);
+}
+
+/**
+ * A collection of arguments for invoking getGatewayHealth.
+ */
+export interface GetGatewayHealthArgs {
+    /**
+     * The OpenFaaS gateway to check in place of the provider's configured gateway.
+     */
+    readonly gateway?: FunctionGateway;
+    /**
+     * The name of the provider's gateway profile to check in place of the provider's configured gateway. Cannot be
+     * combined with gateway.
+     */
+    readonly gatewayProfile?: string;
+}
+
+/**
+ * A collection of values returned by getGatewayHealth.
+ */
+export interface GetGatewayHealthResult {
+    /**
+     * Whether the gateway is reachable and healthy.
+     */
+    readonly healthy: boolean;
+    /**
+     * The reason that the gateway is unhealthy, if it is.
+     */
+    readonly error?: string;
+}
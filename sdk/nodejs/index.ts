@@ -1,4 +1,7 @@
 export * from "./function";
+export * from "./getFunction";
+export * from "./getGatewayHealth";
+export * from "./listFunctions";
 export * from "./provider";
 
 import * as config from "./config";
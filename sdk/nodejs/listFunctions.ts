@@ -0,0 +1,50 @@
+import * as pulumi from "@pulumi/pulumi";
+import { FunctionGateway } from "./function";
+import { GetFunctionResult } from "./getFunction";
+
+/**
+ * Lists the OpenFaaS functions in a namespace, optionally filtered by their labels. The functions need not be managed
+ * by this program.
+ */
+export function listFunctions(args?: ListFunctionsArgs, opts?: pulumi.InvokeOptions): Promise<ListFunctionsResult> {
+    args = args || {};
+    return pulumi.runtime.invoke("openfaas:index:listFunctions", {
+        "namespace": args.namespace,
+        "labels": args.labels,
+        "gateway": args.gateway,
+        "gatewayProfile": args.gatewayProfile,
+    }, opts);
+}
+
+/**
+ * A collection of arguments for invoking listFunctions.
+ */
+export interface ListFunctionsArgs {
+    /**
+     * The namespace to list functions in. Defaults to the provider's configured namespace.
+     */
+    readonly namespace?: string;
+    /**
+     * Labels that the listed functions must carry, with the given values.
+     */
+    readonly labels?: {[key: string]: string};
+    /**
+     * The OpenFaaS gateway to target in place of the provider's configured gateway.
+     */
+    readonly gateway?: FunctionGateway;
+    /**
+     * The name of the provider's gateway profile to target in place of the provider's configured gateway. Cannot be
+     * combined with gateway.
+     */
+    readonly gatewayProfile?: string;
+}
+
+/**
+ * A collection of values returned by listFunctions.
+ */
+export interface ListFunctionsResult {
+    /**
+     * The matching functions, ordered by name.
+     */
+    readonly functions: GetFunctionResult[];
+}
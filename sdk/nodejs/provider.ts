@@ -1,4 +1,5 @@
 import * as pulumi from "@pulumi/pulumi";
+import { FunctionGateway } from "./function";
 
 /**
  * Provides an OpenFaaS Provider resource.
@@ -11,12 +12,46 @@ export class Provider extends pulumi.ProviderResource {
      * @param args The arguments to use to populate this provider's configuration.
      * @param opts A bag of options that control this resource's behavior.
      */
-    constructor(name: string, args: ProviderArgs, opts?: pulumi.ResourceOptions) {
+    constructor(name: string, args?: ProviderArgs, opts?: pulumi.ResourceOptions) {
+        args = args || {};
         super("openfaas", name, {
             "endpoint": args.endpoint,
             "username": args.username,
-            "password": args.password,
+            "password": args.password && pulumi.secret(args.password),
+            "token": args.token && pulumi.secret(args.token),
+            "oidcIssuer": args.oidcIssuer,
+            "oidcClientId": args.oidcClientId,
+            "oidcClientSecret": args.oidcClientSecret && pulumi.secret(args.oidcClientSecret),
+            "oidcToken": args.oidcToken && pulumi.secret(args.oidcToken),
             "tlsSkipVerify": args.tlsSkipVerify,
+            "caCert": args.caCert,
+            "clientCert": args.clientCert,
+            "clientKey": args.clientKey && pulumi.secret(args.clientKey),
+            "proxyUrl": args.proxyUrl,
+            "headers": args.headers && pulumi.secret(args.headers),
+            "credentialCommand": args.credentialCommand,
+            "credentialArgs": args.credentialArgs,
+            "refreshCredentials": args.refreshCredentials,
+            "gateways": args.gateways,
+            "namespace": args.namespace,
+            "defaultLabels": args.defaultLabels,
+            "defaultAnnotations": args.defaultAnnotations,
+            "parallelism": args.parallelism,
+            "maxRetries": args.maxRetries,
+            "requestsPerSecond": args.requestsPerSecond,
+            "burst": args.burst,
+            "skipHealthCheck": args.skipHealthCheck,
+            "offline": args.offline,
+            "userAgentSuffix": args.userAgentSuffix,
+            "maxIdleConns": args.maxIdleConns,
+            "maxIdleConnsPerHost": args.maxIdleConnsPerHost,
+            "idleConnTimeout": args.idleConnTimeout,
+            "http2": args.http2,
+            "connectTimeout": args.connectTimeout,
+            "requestTimeout": args.requestTimeout,
+            "operationTimeout": args.operationTimeout,
+            "lenientPropertyKeys": args.lenientPropertyKeys,
+            "coerceStrings": args.coerceStrings,
         }, opts);
     }
 }
@@ -25,8 +60,41 @@ export class Provider extends pulumi.ProviderResource {
  * The set of arguments for constructing a Provider resource.
  */
 export interface ProviderArgs {
-    readonly endpoint: pulumi.Input<string>;
+    readonly endpoint?: pulumi.Input<string>;
     readonly username?: pulumi.Input<string>;
     readonly password?: pulumi.Input<string>;
+    readonly token?: pulumi.Input<string>;
+    readonly oidcIssuer?: pulumi.Input<string>;
+    readonly oidcClientId?: pulumi.Input<string>;
+    readonly oidcClientSecret?: pulumi.Input<string>;
+    readonly oidcToken?: pulumi.Input<string>;
     readonly tlsSkipVerify?: pulumi.Input<boolean>;
+    readonly caCert?: pulumi.Input<string>;
+    readonly clientCert?: pulumi.Input<string>;
+    readonly clientKey?: pulumi.Input<string>;
+    readonly proxyUrl?: pulumi.Input<string>;
+    readonly headers?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
+    readonly credentialCommand?: pulumi.Input<string>;
+    readonly credentialArgs?: pulumi.Input<pulumi.Input<string>[]>;
+    readonly refreshCredentials?: pulumi.Input<boolean>;
+    readonly gateways?: pulumi.Input<{[name: string]: pulumi.Input<FunctionGateway>}>;
+    readonly namespace?: pulumi.Input<string>;
+    readonly defaultLabels?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
+    readonly defaultAnnotations?: pulumi.Input<{[key: string]: pulumi.Input<string>}>;
+    readonly parallelism?: pulumi.Input<number>;
+    readonly maxRetries?: pulumi.Input<number>;
+    readonly requestsPerSecond?: pulumi.Input<number>;
+    readonly burst?: pulumi.Input<number>;
+    readonly skipHealthCheck?: pulumi.Input<boolean>;
+    readonly offline?: pulumi.Input<boolean>;
+    readonly userAgentSuffix?: pulumi.Input<string>;
+    readonly maxIdleConns?: pulumi.Input<number>;
+    readonly maxIdleConnsPerHost?: pulumi.Input<number>;
+    readonly idleConnTimeout?: pulumi.Input<number>;
+    readonly http2?: pulumi.Input<boolean>;
+    readonly connectTimeout?: pulumi.Input<number>;
+    readonly requestTimeout?: pulumi.Input<number>;
+    readonly operationTimeout?: pulumi.Input<number>;
+    readonly lenientPropertyKeys?: pulumi.Input<boolean>;
+    readonly coerceStrings?: pulumi.Input<boolean>;
 }